@@ -0,0 +1,111 @@
+package window
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type (
+	// Account describes how to obtain credentials for an AWS account.
+	// With neither Profile nor RoleARN set the default credential chain is used.
+	Account struct {
+		Name       string   `json:"name"`
+		Profile    string   `json:"profile,omitempty"`
+		RoleARN    string   `json:"role_arn,omitempty"`
+		ExternalID string   `json:"external_id,omitempty"`
+		Regions    []string `json:"regions,omitempty"`
+
+		// overrides the service endpoints, e.g. to point at an awsfake server
+		Endpoint string `json:"endpoint,omitempty"`
+
+		// overrides the credential chain when set
+		Credentials *credentials.Credentials `json:"-"`
+
+		// resolved with sts:GetCallerIdentity on first refresh
+		Id string `json:"-"`
+
+		me sync.Mutex
+	}
+)
+
+// LoadAccounts reads a json array of account configs
+func LoadAccounts(path string) ([]*Account, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var accounts []*Account
+	if err := json.NewDecoder(f).Decode(&accounts); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+
+}
+
+func (a *Account) Session(region string) (*session.Session, error) {
+
+	config := aws.NewConfig().WithRegion(region)
+	if len(a.Endpoint) > 0 {
+		config = config.WithEndpoint(a.Endpoint)
+	}
+	if a.Credentials != nil {
+		config = config.WithCredentials(a.Credentials)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		Profile:           a.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(a.RoleARN) > 0 {
+		creds := stscreds.NewCredentials(sess, a.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if len(a.ExternalID) > 0 {
+				p.ExternalID = aws.String(a.ExternalID)
+			}
+		})
+		sess = sess.Copy(aws.NewConfig().WithCredentials(creds))
+	}
+
+	return sess, nil
+
+}
+
+// ResolveId looks up the account id once and caches it
+func (a *Account) ResolveId(client STSAPI) (string, error) {
+
+	a.me.Lock()
+	defer a.me.Unlock()
+
+	if len(a.Id) > 0 {
+		return a.Id, nil
+	}
+
+	resp, err := client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	a.Id = aws.StringValue(resp.Account)
+
+	return a.Id, nil
+
+}
+
+// ItemKey namespaces a resource id by account for use in Region.Items
+func ItemKey(accountId, id string) string {
+	return accountId + "/" + id
+}
@@ -28,9 +28,10 @@ type (
 		VpcId string
 
 		// local props
-		Name  string
-		Id    string
-		State string
+		Name      string
+		Id        string
+		AccountId string
+		State     string
 	}
 
 	ACLByNameAsc      []*ACL
@@ -48,9 +49,9 @@ func (a EntryByRuleNumber) Less(i, j int) bool {
 	return *a[i].RuleNumber < *a[j].RuleNumber
 }
 
-func LoadACLs(input *ec2.DescribeNetworkAclsInput) (map[string]*ACL, error) {
+func LoadACLs(client EC2API, input *ec2.DescribeNetworkAclsInput) (map[string]*ACL, error) {
 
-	resp, err := EC2Client.DescribeNetworkAcls(input)
+	resp, err := client.DescribeNetworkAcls(input)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +86,7 @@ type (
 		VirtualizationType string
 
 		Id        string
+		AccountId string
 		Instances []*Instance
 	}
 
@@ -98,9 +99,9 @@ func (a AMIByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadAMIs(input *ec2.DescribeImagesInput) (map[string]*AMI, error) {
+func LoadAMIs(client EC2API, input *ec2.DescribeImagesInput) (map[string]*AMI, error) {
 
-	resp, err := EC2Client.DescribeImages(input)
+	resp, err := client.DescribeImages(input)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +78,7 @@ type (
 
 		Name              string
 		Id                string
+		AccountId         string
 		State             string
 		AvailabilityZones []*AvailabilityZone
 		Instances         []*Instance
@@ -93,11 +94,11 @@ func (a AutoScalingGroupByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadAutoScalingGroups(input *autoscaling.DescribeAutoScalingGroupsInput) (map[string]*AutoScalingGroup, error) {
+func LoadAutoScalingGroups(client AutoScalingAPI, input *autoscaling.DescribeAutoScalingGroupsInput) (map[string]*AutoScalingGroup, error) {
 
 	as_groups := map[string]*AutoScalingGroup{}
 
-	if err := ASClient.DescribeAutoScalingGroupsPages(input, func(page *autoscaling.DescribeAutoScalingGroupsOutput, _ bool) bool {
+	if err := client.DescribeAutoScalingGroupsPages(input, func(page *autoscaling.DescribeAutoScalingGroupsOutput, _ bool) bool {
 		for _, asgroup := range page.AutoScalingGroups {
 			// Instances:               aws.[]Value(asgroup.Instances),               // []*Instance
 			as := &AutoScalingGroup{
@@ -0,0 +1,126 @@
+package awsfake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type (
+	// Fixture is a canned response for a single aws api request.
+	// Query and json protocol requests are matched on Service and
+	// Operation, rest requests (lambda) on Service, Method and Path.
+	// All Params must be present in the request for it to match.
+	Fixture struct {
+		Service     string            `json:"service" yaml:"service"`
+		Operation   string            `json:"operation,omitempty" yaml:"operation,omitempty"`
+		Method      string            `json:"method,omitempty" yaml:"method,omitempty"`
+		Path        string            `json:"path,omitempty" yaml:"path,omitempty"`
+		Params      map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+		Status      int               `json:"status,omitempty" yaml:"status,omitempty"`
+		ContentType string            `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+		Body        string            `json:"body" yaml:"body"`
+	}
+)
+
+// LoadFixtures reads every .json, .yaml and .yml file in dir.
+// Each file holds a list of fixtures.
+func LoadFixtures(dir string) ([]*Fixture, error) {
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var fixtures []*Fixture
+
+	for _, path := range paths {
+		var unmarshal func([]byte, interface{}) error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			unmarshal = json.Unmarshal
+		case ".yaml", ".yml":
+			unmarshal = yaml.Unmarshal
+		default:
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fs []*Fixture
+		if err := unmarshal(data, &fs); err != nil {
+			return nil, &os.PathError{Op: "parse", Path: path, Err: err}
+		}
+		fixtures = append(fixtures, fs...)
+	}
+
+	return fixtures, nil
+
+}
+
+// SaveFixtures writes the fixtures to dir as one <service>.json file per service
+func SaveFixtures(dir string, fixtures []*Fixture) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	services := map[string][]*Fixture{}
+	for _, f := range fixtures {
+		services[f.Service] = append(services[f.Service], f)
+	}
+
+	for service, fs := range services {
+		data, err := json.MarshalIndent(fs, "", "\t")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, service+".json"), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// key identifies the request a fixture responds to
+func (f *Fixture) key() string {
+	params := make([]string, 0, len(f.Params))
+	for k, v := range f.Params {
+		params = append(params, k+"="+v)
+	}
+	sort.Strings(params)
+	return strings.Join([]string{f.Service, f.Operation, f.Method, f.Path, strings.Join(params, "&")}, " ")
+}
+
+func (f *Fixture) matches(req *request) bool {
+	if f.Service != req.service {
+		return false
+	}
+	if len(req.operation) > 0 {
+		if f.Operation != req.operation {
+			return false
+		}
+	} else {
+		if len(f.Method) > 0 && f.Method != req.method {
+			return false
+		}
+		if f.Path != req.path {
+			return false
+		}
+	}
+	for k, v := range f.Params {
+		if req.params[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,335 @@
+// Package awsfake serves canned aws api responses from fixture files
+// so that window can run without aws access, and records real responses
+// into fixtures by proxying requests to aws.
+package awsfake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+type (
+	// Server is an http.Handler speaking the query, ec2, json and
+	// rest-json protocols used by window's service clients.
+	Server struct {
+		Fixtures []*Fixture
+
+		// respond with an error rather than an empty result
+		// when no fixture matches a request
+		Strict bool
+
+		// When Record is set requests are forwarded to aws, signed with
+		// Credentials, and the responses are added to Fixtures, replacing
+		// those of the same request.  If Dir is set the fixtures are saved
+		// there after each response, so Fixtures should be loaded from it
+		// first to keep the ones already recorded.
+		Record      bool
+		Credentials *credentials.Credentials
+		Dir         string
+
+		// Upstream returns the endpoint to forward a recorded request
+		// to.  Defaults to https://<service>.<region>.amazonaws.com
+		Upstream func(service, region string) string
+
+		me sync.Mutex
+	}
+
+	request struct {
+		service   string
+		region    string
+		operation string
+		method    string
+		path      string
+		params    map[string]string
+		body      []byte
+		protocol  string
+	}
+)
+
+const (
+	protocolEC2      = "ec2"
+	protocolQuery    = "query"
+	protocolJSON     = "json"
+	protocolRESTJSON = "rest-json"
+
+	AccountId = "000000000000"
+)
+
+var (
+	// Credentials for clients talking to a Server
+	Credentials = credentials.NewStaticCredentials("AKIDAWSFAKE", "awsfake", "")
+
+	credentialScope = regexp.MustCompile(`Credential=[^/]+/[^/]+/([^/]+)/([^/]+)/aws4_request`)
+
+	// volatileParams change with every request, e.g. the time window and
+	// generated query ids of GetMetricData, so they are left out of
+	// recorded fixtures, which would otherwise never match on replay
+	volatileParams = regexp.MustCompile(`(?i)^((start|end)_?time|MetricDataQueries\..*)$`)
+)
+
+func NewServer(fixtures []*Fixture) *Server {
+	return &Server{Fixtures: fixtures}
+}
+
+func DefaultUpstream(service, region string) string {
+	return fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	req, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.Record {
+		s.record(w, r, req)
+		return
+	}
+
+	if f := s.lookup(req); f != nil {
+		writeFixture(w, f)
+		return
+	}
+
+	if s.Strict {
+		log.Printf("awsfake: no fixture for %s %s%s %v", req.service, req.operation, req.path, req.params)
+		writeError(w, req, "awsfake.NoFixture", fmt.Sprintf("no fixture for %s %s", req.service, req.operation))
+		return
+	}
+
+	writeEmpty(w, req)
+
+}
+
+// lookup returns the matching fixture with the most params
+func (s *Server) lookup(req *request) *Fixture {
+	s.me.Lock()
+	defer s.me.Unlock()
+	var best *Fixture
+	for _, f := range s.Fixtures {
+		if f.matches(req) && (best == nil || len(f.Params) > len(best.Params)) {
+			best = f
+		}
+	}
+	return best
+}
+
+func (s *Server) record(w http.ResponseWriter, r *http.Request, req *request) {
+
+	upstream := s.Upstream
+	if upstream == nil {
+		upstream = DefaultUpstream
+	}
+
+	u, err := url.Parse(upstream(req.service, req.region))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	u.Path = r.URL.Path
+	u.RawQuery = r.URL.RawQuery
+
+	out, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(req.body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for k, vs := range r.Header {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "X-Amz-Date", "X-Amz-Security-Token", "Content-Length":
+		default:
+			out.Header[k] = vs
+		}
+	}
+	if _, err := v4.NewSigner(s.Credentials).Sign(out, bytes.NewReader(req.body), req.service, req.region, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp, err := http.DefaultClient.Do(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	params := map[string]string{}
+	for k, v := range req.params {
+		if !volatileParams.MatchString(k) {
+			params[k] = v
+		}
+	}
+
+	f := &Fixture{
+		Service:     req.service,
+		Operation:   req.operation,
+		Params:      params,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+	}
+	if len(req.operation) == 0 {
+		f.Method = req.method
+		f.Path = req.path
+	}
+	if len(f.Params) == 0 {
+		f.Params = nil
+	}
+
+	// a newer response to the same request replaces the old one
+	s.me.Lock()
+	replaced := false
+	for i, existing := range s.Fixtures {
+		if existing.key() == f.key() {
+			s.Fixtures[i] = f
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.Fixtures = append(s.Fixtures, f)
+	}
+	if len(s.Dir) > 0 {
+		if err := SaveFixtures(s.Dir, s.Fixtures); err != nil {
+			log.Println("awsfake:", err)
+		}
+	}
+	s.me.Unlock()
+
+	writeFixture(w, f)
+
+}
+
+func parseRequest(r *http.Request) (*request, error) {
+
+	m := credentialScope.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return nil, fmt.Errorf("unsigned request")
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &request{
+		region:  m[1],
+		service: m[2],
+		method:  r.Method,
+		path:    r.URL.Path,
+		params:  map[string]string{},
+		body:    body,
+	}
+
+	switch target := r.Header.Get("X-Amz-Target"); {
+	case len(target) > 0:
+		req.protocol = protocolJSON
+		req.operation = target[strings.LastIndex(target, ".")+1:]
+		var v map[string]interface{}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &v); err != nil {
+				return nil, err
+			}
+		}
+		for k, val := range v {
+			switch val.(type) {
+			case string, float64, bool:
+				req.params[k] = fmt.Sprint(val)
+			}
+		}
+	case strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded"):
+		if req.service == "ec2" {
+			req.protocol = protocolEC2
+		} else {
+			req.protocol = protocolQuery
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		req.operation = form.Get("Action")
+		for k := range form {
+			if k != "Action" && k != "Version" {
+				req.params[k] = form.Get(k)
+			}
+		}
+	default:
+		req.protocol = protocolRESTJSON
+		for k := range r.URL.Query() {
+			req.params[k] = r.URL.Query().Get(k)
+		}
+	}
+
+	return req, nil
+
+}
+
+func writeFixture(w http.ResponseWriter, f *Fixture) {
+	if len(f.ContentType) > 0 {
+		w.Header().Set("Content-Type", f.ContentType)
+	}
+	if f.Status != 0 {
+		w.WriteHeader(f.Status)
+	}
+	fmt.Fprint(w, f.Body)
+}
+
+// writeEmpty writes a successful response with no results
+func writeEmpty(w http.ResponseWriter, req *request) {
+	switch req.protocol {
+	case protocolEC2:
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<%sResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"></%[1]sResponse>`, req.operation)
+	case protocolQuery:
+		w.Header().Set("Content-Type", "text/xml")
+		var result string
+		if req.service == "sts" && req.operation == "GetCallerIdentity" {
+			result = `<Account>` + AccountId + `</Account><Arn>arn:aws:iam::` + AccountId + `:user/awsfake</Arn><UserId>AWSFAKE</UserId>`
+		}
+		fmt.Fprintf(w, `<%sResponse><%[1]sResult>%s</%[1]sResult></%[1]sResponse>`, req.operation, result)
+	case protocolJSON:
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		fmt.Fprint(w, `{}`)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func writeError(w http.ResponseWriter, req *request, code, message string) {
+	switch req.protocol {
+	case protocolEC2:
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `<Response><Errors><Error><Code>%s</Code><Message>%s</Message></Error></Errors></Response>`, code, message)
+	case protocolQuery:
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Code>%s</Code><Message>%s</Message></Error></ErrorResponse>`, code, message)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-Errortype", code)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+	}
+}
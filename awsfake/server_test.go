@@ -0,0 +1,241 @@
+package awsfake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func newSession(endpoint string) *session.Session {
+	return session.Must(session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(endpoint).
+		WithCredentials(Credentials).
+		WithMaxRetries(0)))
+}
+
+func TestServerReplay(t *testing.T) {
+
+	fixtures, err := LoadFixtures("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(NewServer(fixtures))
+	defer ts.Close()
+
+	sess := newSession(ts.URL)
+
+	vpcs, err := ec2.New(sess).DescribeVpcs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vpcs.Vpcs) != 1 || aws.StringValue(vpcs.Vpcs[0].VpcId) != "vpc-1a2b3c4d" || len(vpcs.Vpcs[0].Tags) != 1 {
+		t.Errorf("unexpected vpcs: %v", vpcs)
+	}
+
+	subnets, err := ec2.New(sess).DescribeSubnets(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets.Subnets) != 1 || aws.StringValue(subnets.Subnets[0].SubnetId) != "subnet-1" {
+		t.Errorf("unexpected subnets: %v", subnets)
+	}
+
+	// the fixture with matching params is preferred
+	subnets, err = ec2.New(sess).DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: []*string{aws.String("subnet-2")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets.Subnets) != 1 || aws.StringValue(subnets.Subnets[0].SubnetId) != "subnet-2" {
+		t.Errorf("unexpected subnets: %v", subnets)
+	}
+
+	queues, err := sqs.New(sess).ListQueues(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queues.QueueUrls) != 1 || !strings.HasSuffix(aws.StringValue(queues.QueueUrls[0]), "/jobs") {
+		t.Errorf("unexpected queues: %v", queues)
+	}
+
+	funcs, err := lambda.New(sess).ListFunctions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs.Functions) != 1 || aws.StringValue(funcs.Functions[0].FunctionName) != "resize" {
+		t.Errorf("unexpected functions: %v", funcs)
+	}
+
+	identity, err := sts.New(sess).GetCallerIdentity(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(identity.Account) != AccountId {
+		t.Errorf("Expected account %q, got %q", AccountId, aws.StringValue(identity.Account))
+	}
+
+	// no fixture, empty result
+	dbs, err := rds.New(sess).DescribeDBInstances(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dbs.DBInstances) != 0 {
+		t.Errorf("unexpected db instances: %v", dbs)
+	}
+
+}
+
+func TestServerStrict(t *testing.T) {
+
+	server := NewServer(nil)
+	server.Strict = true
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	_, err := ec2.New(newSession(ts.URL)).DescribeVpcs(nil)
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "awsfake.NoFixture" {
+		t.Errorf("Expected awsfake.NoFixture error, got %v", err)
+	}
+
+	_, err = sqs.New(newSession(ts.URL)).ListQueues(nil)
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "awsfake.NoFixture" {
+		t.Errorf("Expected awsfake.NoFixture error, got %v", err)
+	}
+
+}
+
+func TestServerRecord(t *testing.T) {
+
+	const (
+		body         = `<DescribeVpcsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><vpcSet><item><vpcId>vpc-recorded</vpcId></item></vpcSet></DescribeVpcsResponse>`
+		metrics_body = `<GetMetricDataResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/"><GetMetricDataResult><MetricDataResults><member><Id>m0_0</Id><Label>CPUUtilization</Label><StatusCode>Complete</StatusCode><Values><member>42</member></Values></member></MetricDataResults></GetMetricDataResult></GetMetricDataResponse>`
+	)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "Credential=AKIDUPSTREAM/") {
+			t.Errorf("request not signed with upstream credentials: %q", auth)
+		}
+		r.ParseForm()
+		w.Header().Set("Content-Type", "text/xml")
+		if r.Form.Get("Action") == "GetMetricData" {
+			fmt.Fprint(w, metrics_body)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer upstream.Close()
+
+	dir, err := ioutil.TempDir("", "awsfake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// recorded earlier
+	if err := SaveFixtures(dir, []*Fixture{{Service: "sqs", Operation: "ListQueues", Body: "<ListQueuesResponse/>"}}); err != nil {
+		t.Fatal(err)
+	}
+	existing, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := NewServer(existing)
+	recorder.Record = true
+	recorder.Dir = dir
+	recorder.Credentials = credentials.NewStaticCredentials("AKIDUPSTREAM", "secret", "")
+	recorder.Upstream = func(service, region string) string {
+		if (service != "ec2" && service != "monitoring") || region != "us-east-1" {
+			t.Errorf("unexpected upstream %s %s", service, region)
+		}
+		return upstream.URL
+	}
+
+	rs := httptest.NewServer(recorder)
+	defer rs.Close()
+
+	// the same request twice is recorded once
+	for i := 0; i < 2; i++ {
+		if _, err := ec2.New(newSession(rs.URL)).DescribeVpcs(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getMetricData := func(endpoint string, now time.Time) (*cloudwatch.GetMetricDataOutput, error) {
+		return cloudwatch.New(newSession(endpoint)).GetMetricData(&cloudwatch.GetMetricDataInput{
+			StartTime: aws.Time(now.Add(-5 * time.Minute)),
+			EndTime:   aws.Time(now),
+			ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+			MetricDataQueries: []*cloudwatch.MetricDataQuery{{
+				Id: aws.String("m0_0"),
+				MetricStat: &cloudwatch.MetricStat{
+					Metric: &cloudwatch.Metric{Namespace: aws.String("AWS/EC2"), MetricName: aws.String("CPUUtilization")},
+					Period: aws.Int64(300),
+					Stat:   aws.String("Average"),
+				},
+			}},
+		})
+	}
+	if _, err := getMetricData(rs.URL, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := map[string]*Fixture{}
+	for _, f := range fixtures {
+		ops[f.Operation] = f
+	}
+	if len(fixtures) != 3 || ops["ListQueues"] == nil || ops["DescribeVpcs"] == nil || ops["GetMetricData"] == nil {
+		t.Fatalf("unexpected fixtures: %v", fixtures)
+	}
+	if ops["DescribeVpcs"].Body != body {
+		t.Errorf("unexpected DescribeVpcs body: %s", ops["DescribeVpcs"].Body)
+	}
+	for k := range ops["GetMetricData"].Params {
+		if k != "ScanBy" {
+			t.Errorf("Expected volatile param %s left out", k)
+		}
+	}
+
+	// replay the recording
+	ts := httptest.NewServer(NewServer(fixtures))
+	defer ts.Close()
+
+	vpcs, err := ec2.New(newSession(ts.URL)).DescribeVpcs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vpcs.Vpcs) != 1 || aws.StringValue(vpcs.Vpcs[0].VpcId) != "vpc-recorded" {
+		t.Errorf("unexpected vpcs: %v", vpcs)
+	}
+
+	data, err := getMetricData(ts.URL, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.MetricDataResults) != 1 || len(data.MetricDataResults[0].Values) != 1 || aws.Float64Value(data.MetricDataResults[0].Values[0]) != 42 {
+		t.Errorf("unexpected metric data: %v", data)
+	}
+
+}
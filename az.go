@@ -21,6 +21,7 @@ type (
 
 		Name                 string
 		Id                   string
+		AccountId            string
 		Instances            []*Instance
 		ENIs                 []*ENI
 		DBInstances          []*DBInstance
@@ -37,9 +38,9 @@ func (a AvailabilityZoneByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadAvailabilityZones(input *ec2.DescribeAvailabilityZonesInput) (map[string]*AvailabilityZone, error) {
+func LoadAvailabilityZones(client EC2API, input *ec2.DescribeAvailabilityZonesInput) (map[string]*AvailabilityZone, error) {
 
-	resp, err := EC2Client.DescribeAvailabilityZones(input)
+	resp, err := client.DescribeAvailabilityZones(input)
 	if err != nil {
 		return nil, err
 	}
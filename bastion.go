@@ -0,0 +1,276 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/emptyinterface/window/sysinfo"
+	"golang.org/x/crypto/ssh"
+)
+
+type (
+	// Bastion is a jump host instance ssh connections are proxied through
+	Bastion struct {
+		// host:port
+		Host string `json:"host"`
+		User string `json:"user"`
+		// name of the pem file in the ssh key path, without .pem
+		Key string `json:"key"`
+
+		// the bastion instance, if found by tag
+		instanceId string
+	}
+
+	// Bastions routes instance ssh connections through jump hosts, e.g.
+	//
+	//	{
+	//		"tag": "window:bastion",
+	//		"hosts": {
+	//			"vpc-1a2b3c4d": {"host": "bastion.example.com:22", "user": "ec2-user", "key": "ops"},
+	//			"subnet-5e6f7a8b": {"host": "10.0.0.5:22", "user": "ubuntu", "key": "ops"}
+	//		}
+	//	}
+	//
+	// A subnet's bastion is used before its vpc's.  Failing both, a running
+	// instance in the vpc with the tag is used, logging in as the tag's value
+	// with its own key.
+	Bastions struct {
+		// by subnet or vpc id
+		Hosts map[string]*Bastion `json:"hosts"`
+		Tag   string              `json:"tag,omitempty"`
+
+		// connected and connecting bastions by host
+		clients map[string]*ssh.Client
+		dialing map[string]*bastionDial
+		me      sync.Mutex
+	}
+
+	// bastionDial is a connection to a bastion in progress, shared by
+	// everything waiting on it
+	bastionDial struct {
+		done   chan struct{}
+		client *ssh.Client
+		err    error
+	}
+
+	// BastionError is returned when the bastion itself can't be reached
+	// or rejects the connection, so no user of the instance will get
+	// through.  Err is a *HostKeyError if the bastion's host key was
+	// rejected.
+	BastionError struct {
+		Host string
+		Err  error
+	}
+)
+
+const (
+	DefaultBastionTag  = "window:bastion"
+	DefaultBastionUser = "ec2-user"
+)
+
+// LoadBastions reads a json bastion config
+func LoadBastions(path string) (*Bastions, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b Bastions
+	if err := json.NewDecoder(f).Decode(&b); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for id, bastion := range b.Hosts {
+		if len(bastion.Host) == 0 || len(bastion.Key) == 0 {
+			return nil, fmt.Errorf("%s: %s: host and key are required", path, id)
+		}
+		if len(bastion.User) == 0 {
+			bastion.User = DefaultBastionUser
+		}
+	}
+
+	return &b, nil
+
+}
+
+// For returns the bastion to reach an instance through, or nil
+// to connect directly
+func (b *Bastions) For(inst *Instance) *Bastion {
+
+	if bastion, exists := b.Hosts[inst.SubnetId]; exists {
+		return bastion
+	}
+	if bastion, exists := b.Hosts[inst.VpcId]; exists {
+		return bastion
+	}
+
+	tag := b.Tag
+	if len(tag) == 0 {
+		tag = DefaultBastionTag
+	}
+	if inst.VPC == nil || hasTag(inst, tag) {
+		return nil
+	}
+	for _, other := range inst.VPC.Instances {
+		if other.State != ec2.InstanceStateNameRunning || len(other.PublicIpAddress) == 0 || !hasTag(other, tag) {
+			continue
+		}
+		user := TagOrDefault(other.Tags, tag)
+		if len(user) == 0 {
+			user = DefaultBastionUser
+		}
+		return &Bastion{
+			Host:       other.PublicIpAddress + ":22",
+			User:       user,
+			Key:        other.KeyName,
+			instanceId: other.InstanceId,
+		}
+	}
+
+	return nil
+
+}
+
+func hasTag(inst *Instance, key string) bool {
+	for _, tag := range inst.Tags {
+		if tag.Key != nil && *tag.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *BastionError) Error() string {
+	return fmt.Sprintf("bastion %s: %v", e.Host, e.Err)
+}
+
+// Dial returns a dial func that connects through bastion with its key
+// pair's keys, reusing one connection to it for all its instances.  The bastion's host key is
+// checked against known hosts, if set, under its instance id or host.
+func (b *Bastions) Dial(bastion *Bastion, keys []KeyProvider, kh *KnownHosts) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+
+		client, err := b.client(bastion, keys, kh)
+		if err != nil {
+			return nil, &BastionError{Host: bastion.Host, Err: err}
+		}
+
+		conn, err := client.Dial(network, addr)
+		if err != nil {
+			// the bastion refusing to connect to addr doesn't mean
+			// the connection to it is broken
+			if _, ok := err.(*ssh.OpenChannelError); !ok {
+				b.drop(bastion, client)
+			}
+			return nil, err
+		}
+
+		return conn, nil
+
+	}
+}
+
+// client returns the connection to bastion, connecting if there isn't
+// one.  Only one connection to a bastion is attempted at a time and the
+// lock isn't held while connecting, so a slow bastion holds up only its
+// own instances.
+func (b *Bastions) client(bastion *Bastion, keys []KeyProvider, kh *KnownHosts) (*ssh.Client, error) {
+
+	b.me.Lock()
+	if client, exists := b.clients[bastion.Host]; exists {
+		b.me.Unlock()
+		return client, nil
+	}
+	if d, exists := b.dialing[bastion.Host]; exists {
+		b.me.Unlock()
+		<-d.done
+		return d.client, d.err
+	}
+	d := &bastionDial{done: make(chan struct{})}
+	if b.dialing == nil {
+		b.dialing = map[string]*bastionDial{}
+	}
+	b.dialing[bastion.Host] = d
+	b.me.Unlock()
+
+	d.client, d.err = dialBastion(bastion, keys, kh)
+
+	b.me.Lock()
+	delete(b.dialing, bastion.Host)
+	if d.err == nil {
+		if b.clients == nil {
+			b.clients = map[string]*ssh.Client{}
+		}
+		b.clients[bastion.Host] = d.client
+	}
+	b.me.Unlock()
+	close(d.done)
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	// forget the connection once it closes
+	go func(client *ssh.Client) {
+		client.Wait()
+		b.drop(bastion, client)
+	}(d.client)
+
+	return d.client, nil
+
+}
+
+func dialBastion(bastion *Bastion, keys []KeyProvider, kh *KnownHosts) (*ssh.Client, error) {
+
+	signers, err := signers(keys, bastion.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	// the handshake error only carries the host key error's message
+	var hostKeyErr error
+	callback := ssh.InsecureIgnoreHostKey()
+	if kh != nil {
+		id := bastion.instanceId
+		if len(id) == 0 {
+			id = "bastion:" + bastion.Host
+		}
+		check := kh.Callback(id, nil)
+		callback = func(host string, remote net.Addr, key ssh.PublicKey) error {
+			hostKeyErr = check(host, remote, key)
+			return hostKeyErr
+		}
+	}
+
+	client, err := ssh.Dial("tcp", bastion.Host, &ssh.ClientConfig{
+		User:            bastion.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: callback,
+		Timeout:         sysinfo.DialTimeout,
+	})
+	if err != nil {
+		if hostKeyErr != nil {
+			return nil, hostKeyErr
+		}
+		return nil, err
+	}
+
+	return client, nil
+
+}
+
+// drop closes client if it is still bastion's connection
+func (b *Bastions) drop(bastion *Bastion, client *ssh.Client) {
+	b.me.Lock()
+	defer b.me.Unlock()
+	if b.clients[bastion.Host] == client {
+		delete(b.clients, bastion.Host)
+		client.Close()
+	}
+}
@@ -0,0 +1,383 @@
+package window
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/crypto/ssh"
+)
+
+type (
+	// bastionServer forwards direct-tcpip channels for one authorized key
+	bastionServer struct {
+		Host    string
+		HostKey ssh.PublicKey
+		l       net.Listener
+		conns   int32
+		open    []net.Conn
+		me      sync.Mutex
+	}
+)
+
+// unforwardedHost is refused by bastionServer
+const unforwardedHost = "10.255.255.1"
+
+func newBastionServer(t *testing.T, authorized ssh.PublicKey) *bastionServer {
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, errors.New("unauthorized")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &bastionServer{
+		Host:    l.Addr().String(),
+		HostKey: signer.PublicKey(),
+		l:       l,
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&s.conns, 1)
+			s.me.Lock()
+			s.open = append(s.open, conn)
+			s.me.Unlock()
+			go s.serve(conn, config)
+		}
+	}()
+
+	return s
+
+}
+
+func (s *bastionServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for c := range chans {
+
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if c.ChannelType() != "direct-tcpip" || ssh.Unmarshal(c.ExtraData(), &target) != nil {
+			c.Reject(ssh.UnknownChannelType, "direct-tcpip only")
+			continue
+		}
+		if target.Host == unforwardedHost {
+			c.Reject(ssh.ConnectionFailed, "no route to host")
+			continue
+		}
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			c.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := c.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			defer channel.Close()
+			defer remote.Close()
+			go io.Copy(remote, channel)
+			io.Copy(channel, remote)
+		}()
+
+	}
+
+}
+
+// Connections returns the number of connections accepted
+func (s *bastionServer) Connections() int {
+	return int(atomic.LoadInt32(&s.conns))
+}
+
+// Break closes every open connection
+func (s *bastionServer) Break() {
+	s.me.Lock()
+	defer s.me.Unlock()
+	for _, conn := range s.open {
+		conn.Close()
+	}
+	s.open = nil
+}
+
+func (s *bastionServer) Close() error {
+	s.Break()
+	return s.l.Close()
+}
+
+// newEchoServer echoes back whatever each connection sends
+func newEchoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l
+}
+
+func echo(dial func(network, addr string) (net.Conn, error), addr string) error {
+	conn, err := dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return err
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+	if string(buf) != "ping" {
+		return fmt.Errorf("Expected ping, got %q", buf)
+	}
+	return nil
+}
+
+func TestLoadBastions(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-bastions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		json string
+		err  string
+	}{
+		{`{"hosts": {"vpc-1": {"host": "bastion:22", "key": "ops"}}}`, ""},
+		{`{"hosts": {"vpc-1": {"host": "bastion:22"}}}`, "host and key are required"},
+		{`{"hosts": []}`, "cannot unmarshal"},
+	} {
+		path := filepath.Join(dir, "bastions.json")
+		if err := ioutil.WriteFile(path, []byte(test.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		b, err := LoadBastions(path)
+		switch {
+		case len(test.err) == 0 && err != nil:
+			t.Errorf("%s: %v", test.json, err)
+		case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: Expected error %q, got %v", test.json, test.err, err)
+		case err == nil && b.Hosts["vpc-1"].User != DefaultBastionUser:
+			t.Errorf("Expected user to default to %s, got %q", DefaultBastionUser, b.Hosts["vpc-1"].User)
+		}
+	}
+
+}
+
+func TestBastionsFor(t *testing.T) {
+
+	vpc := &VPC{}
+	web := &Instance{InstanceId: "i-1", VpcId: "vpc-1", SubnetId: "subnet-1", VPC: vpc}
+	jump := &Instance{
+		InstanceId:      "i-2",
+		VpcId:           "vpc-1",
+		SubnetId:        "subnet-2",
+		VPC:             vpc,
+		State:           ec2.InstanceStateNameRunning,
+		PublicIpAddress: "203.0.113.1",
+		KeyName:         "ops",
+		Tags:            []*ec2.Tag{{Key: aws.String(DefaultBastionTag), Value: aws.String("ubuntu")}},
+	}
+	vpc.Instances = []*Instance{web, jump}
+
+	vpc_bastion := &Bastion{Host: "vpc:22"}
+	subnet_bastion := &Bastion{Host: "subnet:22"}
+
+	b := &Bastions{Hosts: map[string]*Bastion{"vpc-1": vpc_bastion}}
+	if bastion := b.For(web); bastion != vpc_bastion {
+		t.Errorf("Expected the vpc's bastion, got %v", bastion)
+	}
+
+	b.Hosts["subnet-1"] = subnet_bastion
+	if bastion := b.For(web); bastion != subnet_bastion {
+		t.Errorf("Expected the subnet's bastion, got %v", bastion)
+	}
+
+	b = &Bastions{}
+	bastion := b.For(web)
+	if bastion == nil || bastion.Host != "203.0.113.1:22" || bastion.User != "ubuntu" || bastion.Key != "ops" || bastion.instanceId != "i-2" {
+		t.Errorf("Expected the tagged instance, got %+v", bastion)
+	}
+	if bastion := b.For(jump); bastion != nil {
+		t.Errorf("Expected the bastion to be reached directly, got %+v", bastion)
+	}
+
+	jump.State = ec2.InstanceStateNameStopped
+	if bastion := b.For(web); bastion != nil {
+		t.Errorf("Expected no bastion, got %+v", bastion)
+	}
+
+}
+
+func TestBastionsDial(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-bastion-dial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	priv := writePrivateKey(t, filepath.Join(dir, "ops.pem"))
+	writePrivateKey(t, filepath.Join(dir, "other.pem"))
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []KeyProvider{&KeyFiles{Dir: dir}}
+
+	kh, err := LoadKnownHosts(filepath.Join(dir, "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newBastionServer(t, signer.PublicKey())
+	defer s.Close()
+	target := newEchoServer(t)
+	defer target.Close()
+
+	b := &Bastions{}
+	bastion := &Bastion{Host: s.Host, User: DefaultBastionUser, Key: "ops"}
+
+	// instances dialing at once share one connection
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := echo(b.Dial(bastion, keys, kh), target.Addr().String()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := s.Connections(); n != 1 {
+		t.Errorf("Expected 1 connection, got %d", n)
+	}
+	if err := kh.Callback("bastion:"+s.Host, nil)("", nil, s.HostKey); err != nil {
+		t.Errorf("Expected the bastion's host key pinned, got %v", err)
+	}
+
+	// a refused instance leaves the connection open
+	_, err = b.Dial(bastion, keys, kh)("tcp", unforwardedHost+":22")
+	if _, ok := err.(*ssh.OpenChannelError); !ok {
+		t.Errorf("Expected an open channel error, got %v", err)
+	}
+	if err := echo(b.Dial(bastion, keys, kh), target.Addr().String()); err != nil {
+		t.Error(err)
+	}
+	if n := s.Connections(); n != 1 {
+		t.Errorf("Expected the connection kept, got %d connections", n)
+	}
+
+	// a broken connection is dropped and replaced
+	s.Break()
+	if err := echo(b.Dial(bastion, keys, kh), target.Addr().String()); err != nil {
+		if err := echo(b.Dial(bastion, keys, kh), target.Addr().String()); err != nil {
+			t.Errorf("Expected a new connection, got %v", err)
+		}
+	}
+	if n := s.Connections(); n != 2 {
+		t.Errorf("Expected 2 connections, got %d", n)
+	}
+
+	// keys the bastion doesn't authorize
+	_, err = (&Bastions{}).Dial(&Bastion{Host: s.Host, User: DefaultBastionUser, Key: "other"}, keys, kh)("tcp", target.Addr().String())
+	if be, ok := err.(*BastionError); !ok || !strings.Contains(be.Err.Error(), "handshake failed") {
+		t.Errorf("Expected a bastion handshake error, got %v", err)
+	}
+
+	// a bastion whose host key changed
+	changed := newBastionServer(t, signer.PublicKey())
+	defer changed.Close()
+	if err := kh.Callback("bastion:"+changed.Host, nil)("", nil, newHostKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Dial(&Bastion{Host: changed.Host, User: DefaultBastionUser, Key: "ops"}, keys, kh)("tcp", target.Addr().String())
+	if be, ok := err.(*BastionError); !ok {
+		t.Errorf("Expected a bastion error, got %v", err)
+	} else if _, ok := be.Err.(*HostKeyError); !ok {
+		t.Errorf("Expected a host key error, got %v", be.Err)
+	}
+
+	// which fails the instance's poll without trying other users
+	p := NewInstancePoller(1)
+	p.Keys = keys
+	p.KnownHosts = kh
+	p.Bastions = &Bastions{Hosts: map[string]*Bastion{"vpc-1": {Host: changed.Host, User: DefaultBastionUser, Key: "ops"}}}
+	inst := runningInstance("i-1")
+	inst.VpcId = "vpc-1"
+	inst.KeyName = "ops"
+	inst.PrivateIpAddress = "10.0.0.9"
+	state := &instancePollState{}
+	before := changed.Connections()
+	err = inst.poll(p, state)
+	if _, ok := err.(*BastionError); !ok || err != state.hostKeyErr {
+		t.Errorf("Expected the bastion's host key error, got %v", err)
+	}
+	if n := changed.Connections() - before; n != 1 {
+		t.Errorf("Expected 1 connection to the bastion, got %d", n)
+	}
+
+}
@@ -26,8 +26,9 @@ type (
 		// The type of VPN connection the customer gateway supports (ipsec.1).
 		Type string
 
-		Name string
-		Id   string
+		Name      string
+		Id        string
+		AccountId string
 	}
 
 	CustomerGatewayByNameAsc []*CustomerGateway
@@ -46,9 +47,9 @@ func (cgw *CustomerGateway) String() string {
 	return ""
 }
 
-func LoadCustomerGateways(input *ec2.DescribeCustomerGatewaysInput) (map[string]*CustomerGateway, error) {
+func LoadCustomerGateways(client EC2API, input *ec2.DescribeCustomerGatewaysInput) (map[string]*CustomerGateway, error) {
 
-	resp, err := EC2Client.DescribeCustomerGateways(input)
+	resp, err := client.DescribeCustomerGateways(input)
 	if err != nil {
 		return nil, err
 	}
@@ -97,10 +97,11 @@ type (
 		// The unit of the alarm's associated metric.
 		Unit string
 
-		Name   string
-		Id     string
-		State  string
-		Region *Region
+		Name      string
+		Id        string
+		AccountId string
+		State     string
+		Region    *Region
 
 		AlarmActionSNSs                         []*SNSTopic
 		AlarmActionAutoScalingGroups            []*AutoScalingGroup
@@ -119,11 +120,11 @@ func (a CloudWatchAlarmByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadCloudWatchAlarms(input *cloudwatch.DescribeAlarmsInput) (map[string]*CloudWatchAlarm, error) {
+func LoadCloudWatchAlarms(client CloudWatchAPI, input *cloudwatch.DescribeAlarmsInput) (map[string]*CloudWatchAlarm, error) {
 
 	alarms := map[string]*CloudWatchAlarm{}
 
-	if err := CloudWatchClient.DescribeAlarmsPages(input, func(p *cloudwatch.DescribeAlarmsOutput, _ bool) bool {
+	if err := client.DescribeAlarmsPages(input, func(p *cloudwatch.DescribeAlarmsOutput, _ bool) bool {
 		for _, ma := range p.MetricAlarms {
 			alarm := &CloudWatchAlarm{
 				ActionsEnabled: aws.BoolValue(ma.ActionsEnabled),
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/emptyinterface/window/awsfake"
+)
+
+var (
+	host     = flag.String("host", "localhost:4566", "host to serve http on")
+	fixtures = flag.String("fixtures", "fixtures", "directory of json/yaml fixture files")
+	record   = flag.Bool("record", false, "proxy requests to aws and record the responses into the fixtures directory")
+	strict   = flag.Bool("strict", false, "return an error for requests without a fixture instead of an empty result")
+)
+
+func init() {
+	log.SetFlags(log.Lshortfile | log.LstdFlags)
+}
+
+func main() {
+
+	flag.Parse()
+
+	// recording adds to the fixtures already there
+	fs, err := awsfake.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintln(os.Stderr, "loaded", len(fs), "fixtures")
+
+	server := awsfake.NewServer(fs)
+	server.Strict = *strict
+
+	if *record {
+		sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.Record = true
+		server.Credentials = sess.Config.Credentials
+		server.Dir = *fixtures
+	}
+
+	fmt.Println("ready")
+	log.Fatal(http.ListenAndServe(*host, server))
+
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emptyinterface/window"
+)
+
+type (
+	// API serves the resources of a region as json:
+	//
+	//	/api/v1/                 item counts by kind
+	//	/api/v1/<kind>           items of a kind, e.g. /api/v1/instances
+	//	/api/v1/items/<key>          a single item by its Items key
+	//	/api/v1/items/<key>/metrics  the item's metric history
+	//
+	// Lists may be filtered with ?vpc=, ?az=, ?tag=key[=value] and ?state=.
+	// Metrics may be filtered with ?name=a,b and limited with ?from= and ?to=,
+	// given as unix seconds or RFC3339.
+	// Links between resources are given as Items keys.
+	API struct {
+		region func(*http.Request) (*window.Region, error)
+	}
+)
+
+func NewAPI(region func(*http.Request) (*window.Region, error)) *API {
+	return &API{region: region}
+}
+
+func (api *API) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	region, err := api.region(req)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	region.Lock()
+	defer region.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/api/v1/")
+
+	switch {
+	case len(path) == 0:
+		writeJSON(w, region.Kinds())
+
+	case strings.HasPrefix(path, "items/") && strings.HasSuffix(path, "/metrics"):
+		key := strings.TrimSuffix(strings.TrimPrefix(path, "items/"), "/metrics")
+		query := req.URL.Query()
+		var (
+			names    []string
+			from, to time.Time
+		)
+		if len(query.Get("name")) > 0 {
+			names = strings.Split(query.Get("name"), ",")
+		}
+		for _, param := range []struct {
+			name string
+			t    *time.Time
+		}{{"from", &from}, {"to", &to}} {
+			if v := query.Get(param.name); len(v) > 0 {
+				if *param.t, err = parseAt(v); err != nil {
+					writeJSONError(w, http.StatusBadRequest, param.name+": "+err.Error())
+					return
+				}
+			}
+		}
+		metrics, exists := region.ItemMetrics(key, names, from, to)
+		if !exists {
+			writeJSONError(w, http.StatusNotFound, key+" not found")
+			return
+		}
+		writeJSON(w, metrics)
+
+	case strings.HasPrefix(path, "items/"):
+		key := strings.TrimPrefix(path, "items/")
+		items, err := region.ItemsJSON([]string{key})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(items) == 0 {
+			writeJSONError(w, http.StatusNotFound, key+" not found")
+			return
+		}
+		writeJSON(w, items[0])
+
+	default:
+		query := req.URL.Query()
+		items, err := region.ItemsJSON(region.Query(window.ItemQuery{
+			Kind:  path,
+			VPC:   query.Get("vpc"),
+			AZ:    query.Get("az"),
+			Tag:   query.Get("tag"),
+			State: query.Get("state"),
+		}))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, items)
+	}
+
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
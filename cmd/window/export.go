@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emptyinterface/window"
+)
+
+// export refreshes each region once and writes it to a snapshot
+// file that can be served without aws access using -snapshot.
+//
+//	window export -regions us-east-1 -o inventory.json.gz
+func export(args []string) {
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	flag.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	out := fs.String("o", "window-{region}.json.gz", "file to write, {region} is replaced with the region key (.gz to compress)")
+	fs.Parse(args)
+
+	fleet := awsFleet()
+
+	if len(fleet.Regions) > 1 && !strings.Contains(*out, "{region}") {
+		log.Fatalf("-o %s must contain {region} to export %d regions", *out, len(fleet.Regions))
+	}
+
+	start := time.Now()
+	if err := fleet.Refresh(); err != nil {
+		log.Println(err)
+	}
+	for _, region := range fleet.Regions {
+		for _, errchans := range region.RefreshInstances() {
+			for _, errchan := range errchans {
+				if err := <-errchan; err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
+	fmt.Fprintln(os.Stderr, "refreshed in", time.Since(start))
+
+	for _, region := range fleet.Regions {
+		path := strings.Replace(*out, "{region}", region.Key(), -1)
+		region.Lock()
+		err := window.WriteSnapshot(path, region)
+		region.Unlock()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintln(os.Stderr, "wrote", region.Key(), "to", path)
+	}
+
+}
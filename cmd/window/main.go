@@ -1,15 +1,20 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/emptyinterface/window"
+	"github.com/emptyinterface/window/awsfake"
 	"golang.org/x/net/websocket"
 )
 
@@ -18,35 +23,223 @@ var (
 	cert_key = flag.String("cert_key", "cert/key.pem", "keys for https")
 	cert     = flag.String("cert", "cert/cert.pem", "keys for https")
 	ssh_keys = flag.String("ssh_keys", "$HOME/.ssh", "ssh keys to servers")
+	regions  = flag.String("regions", "", "comma separated list of regions to serve (defaults to $AWS_REGION)")
+	accounts = flag.String("accounts", "", "json file of account configs (defaults to the default credential chain)")
+	aws_fake = flag.String("awsfake", "", "send all aws api calls to this awsfake server (e.g. http://localhost:4566)")
+	metrics  = flag.String("metrics", "", "json file of additional cloudwatch metric definitions to collect")
+	sys_info = flag.String("sysinfo", "", "json file selecting additional sysinfo metrics to collect over ssh by default, tag or instance")
 
-	concurrency       = flag.Int("concurrency", 40, "how many ssh/api requests allowed concurrently")
-	rate              = flag.Int("rate", 300, "how many ssh/api calls can be made within a given period (rate_interval)")
-	rate_interval     = flag.Duration("rate_interval", time.Second, "the duration constraint to the ssh/api call rate")
+	concurrency       = flag.Int("concurrency", 40, "how many api requests allowed concurrently")
+	rate              = flag.Int("rate", 300, "how many api calls can be made within a given period (rate_interval)")
+	rate_interval     = flag.Duration("rate_interval", time.Second, "the duration constraint to the api call rate")
 	region_interval   = flag.Duration("region_interval", 5*time.Minute, "polling interval for aws service information")
-	instance_interval = flag.Duration("instance_interval", 60*time.Second, "polling interval for instance sysinfo stats")
+	instance_interval = flag.Duration("instance_interval", 60*time.Second, "polling interval for instance sysinfo stats, polls are spread evenly across it")
+	ssh_concurrency   = flag.Int("ssh_concurrency", window.DefaultSSHSessions, "how many instance ssh polls are allowed concurrently")
+	ssh_stream        = flag.Duration("ssh_stream", 0, "stream instance sysinfo at this interval over one ssh session each instead of polling (0 polls)")
+	ssh_backoff       = flag.Duration("ssh_backoff", window.DefaultMinBackoff, "how long to wait before retrying an unreachable instance, doubling with each failure")
+	ssh_backoff_max   = flag.Duration("ssh_backoff_max", window.DefaultMaxBackoff, "the most to wait before retrying an unreachable instance")
+
+	known_hosts      = flag.String("known_hosts", "$HOME/.ssh/window_known_hosts", "file to pin instance ssh host keys in on first use, by instance id (empty accepts any key)")
+	bastions         = flag.String("bastions", "", "json file of jump hosts to reach instances through by vpc or subnet, also enables instances tagged window:bastion as jump hosts for their vpc")
+	verify_host_keys = flag.Bool("verify_host_keys", false, "verify new instance host keys against the fingerprints in their ec2 console output before pinning")
+	ssh_key_map      = flag.String("ssh_key_map", "", "json file of ec2 key pair names to private key paths, for keys not named <key pair>.pem in ssh_keys")
+	ssh_agent        = flag.Bool("ssh_agent", false, "also offer the keys of the ssh-agent at $SSH_AUTH_SOCK")
+	ssh_user_tag     = flag.String("ssh_user_tag", window.DefaultSSHUserTag, "instance tag naming its ssh login user, otherwise the user is guessed from its ami")
+
+	history_resolution = flag.Duration("history_resolution", window.DefaultHistory.Resolution, "minimum time between points of each resource metric's history")
+	history_retention  = flag.Duration("history_retention", window.DefaultHistory.Retention, "how long to keep each resource metric's history")
+
+	snapshot        = flag.String("snapshot", "", "serve these comma separated snapshot files (see window export) instead of polling aws")
+	store           = flag.String("store", "", "directory to keep a snapshot of each refresh in, for browsing with ?at=")
+	store_retention = flag.Duration("store_retention", 7*24*time.Hour, "how long to keep snapshots (0 keeps all)")
+	store_max       = flag.Int("store_max", 0, "how many snapshots to keep per region (0 keeps all)")
+
+	metrics_tags = flag.String("metrics_tags", "Name", "comma separated instance tags to label /metrics with")
 
 	dev = flag.Bool("dev", false, "recompile templates on refresh")
 )
 
 func init() {
 	log.SetFlags(log.Lshortfile | log.LstdFlags)
-	if _, exists := os.LookupEnv("AWS_REGION"); !exists {
-		log.Fatal("Must load AWS_* environment variables")
+}
+
+// parseAt parses an ?at= time, either RFC3339 or unix seconds
+func parseAt(at string) (time.Time, error) {
+	if n, err := strconv.ParseInt(at, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, at)
+}
+
+// awsFleet builds the fleet of regions to poll from the flags
+func awsFleet() *window.Fleet {
+
+	if len(*regions) == 0 {
+		*regions = os.Getenv("AWS_REGION")
+	}
+
+	account_configs := []*window.Account{{}}
+	if len(*accounts) > 0 {
+		var err error
+		if account_configs, err = window.LoadAccounts(*accounts); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(*aws_fake) > 0 {
+		for _, account := range account_configs {
+			account.Endpoint = *aws_fake
+			account.Credentials = awsfake.Credentials
+		}
+	}
+
+	var default_regions []string
+	if len(*regions) > 0 {
+		default_regions = strings.Split(*regions, ",")
+	}
+
+	var metric_defs []*window.MetricDefinition
+	if len(*metrics) > 0 {
+		var err error
+		if metric_defs, err = window.LoadMetricDefinitions(*metrics); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fleet, err := window.NewFleet(account_configs, default_regions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(fleet.Regions) == 0 {
+		log.Fatal("Must specify -regions or load AWS_* environment variables")
+	}
+	var kh *window.KnownHosts
+	if len(*known_hosts) > 0 {
+		kh, err = window.LoadKnownHosts(os.ExpandEnv(*known_hosts))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	key_files, err := window.LoadKeyFiles(os.ExpandEnv(*ssh_keys), *ssh_key_map)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys := []window.KeyProvider{key_files}
+	if *ssh_agent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if len(sock) == 0 {
+			log.Fatal("-ssh_agent requires $SSH_AUTH_SOCK")
+		}
+		keys = append(keys, window.NewAgentKeys(sock))
+	}
+
+	var sysinfo_config *window.SysInfoConfig
+	if len(*sys_info) > 0 {
+		if sysinfo_config, err = window.LoadSysInfoConfig(*sys_info); err != nil {
+			log.Fatal(err)
+		}
 	}
+
+	var jumps *window.Bastions
+	if len(*bastions) > 0 {
+		if jumps, err = window.LoadBastions(*bastions); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, region := range fleet.Regions {
+		region.Throttle = window.NewThrottle(*concurrency, *rate, *rate_interval)
+		region.SetSSHKeyPath(os.ExpandEnv(*ssh_keys))
+		region.Poller = window.NewInstancePoller(*ssh_concurrency)
+		region.Poller.Stream = *ssh_stream
+		region.Poller.KnownHosts = kh
+		region.Poller.VerifyConsole = *verify_host_keys
+		region.Poller.Bastions = jumps
+		region.Poller.Keys = keys
+		region.Poller.SysInfo = sysinfo_config
+		region.Poller.Users = []window.UserProvider{window.TagUser(*ssh_user_tag), window.DefaultAMIUsers}
+		region.Poller.MinBackoff = *ssh_backoff
+		region.Poller.MaxBackoff = *ssh_backoff_max
+		region.MetricDefinitions = metric_defs
+		region.History = window.History{
+			Resolution: *history_resolution,
+			Retention:  *history_retention,
+		}
+	}
+
+	return fleet
+
 }
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		export(os.Args[2:])
+		return
+	}
+
 	flag.Parse()
 
-	region := window.NewRegion(os.Getenv("AWS_REGION"))
-	region.Throttle = window.NewThrottle(*concurrency, *rate, *rate_interval)
-	region.SetSSHKeyPath(os.ExpandEnv(*ssh_keys))
+	// offline mode serves saved snapshots without calling aws
+	offline := len(*snapshot) > 0
+
+	var fleet *window.Fleet
+	if offline {
+		var err error
+		if fleet, err = window.NewSnapshotFleet(strings.Split(*snapshot, ",")); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		fleet = awsFleet()
+	}
+
+	// the selected region is stored in a cookie so that
+	// all paths in the templates remain region agnostic.
+	// api clients may pass ?region= instead.
+	selectedRegion := func(req *http.Request) *window.Region {
+		if region := fleet.Region(req.URL.Query().Get("region")); region != nil {
+			return region
+		}
+		if c, err := req.Cookie("region"); err == nil {
+			if region := fleet.Region(c.Value); region != nil {
+				return region
+			}
+		}
+		return fleet.Regions[0]
+	}
+
+	var snapshots *window.Store
+	if len(*store) > 0 {
+		var err error
+		if snapshots, err = window.NewStore(*store, *store_retention, *store_max); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// regionAt returns the selected region, or its snapshot
+	// as of the request's ?at= time if there is one
+	regionAt := func(req *http.Request) (*window.Region, error) {
+		region := selectedRegion(req)
+		at := req.URL.Query().Get("at")
+		if len(at) == 0 {
+			return region, nil
+		}
+		if snapshots == nil {
+			return nil, fmt.Errorf("no snapshot store, see -store")
+		}
+		t, err := parseAt(at)
+		if err != nil {
+			return nil, err
+		}
+		return snapshots.Load(region.Key(), t)
+	}
 
 	var (
 		templateSet = NewTemplateSet()
 
-		pub = NewPublisher(func(path string) string {
+		// render executes the template for a /data/ path against the region
+		render = func(region *window.Region, path string) string {
 			region.Lock()
 			defer region.Unlock()
 			if *dev {
@@ -89,45 +282,49 @@ func main() {
 			default:
 				return "stfu"
 			}
+		}
+
+		// publisher keys are region key + path
+		pub = NewPublisher(func(key string) string {
+			i := strings.IndexByte(key, '/')
+			if i < 0 {
+				return "bad key"
+			}
+			region, path := fleet.Region(key[:i]), key[i:]
+			if region == nil {
+				return fmt.Sprintf("%q region not found", key[:i])
+			}
+			return render(region, path)
 		})
 	)
 
-	start := time.Now()
-	if err := region.Refresh(); err != nil {
-		log.Fatal(err)
+	save := func(region *window.Region) {
+		if snapshots == nil {
+			return
+		}
+		region.Lock()
+		defer region.Unlock()
+		if err := snapshots.Save(region); err != nil {
+			log.Println(region.Key(), "snapshot:", err)
+		}
 	}
-	fmt.Println("First refresh in", time.Since(start))
 
-	go func() {
-		region_ticker := time.NewTicker(*region_interval)
-		instance_ticker := time.NewTicker(*instance_interval)
-		defer region_ticker.Stop()
-		defer instance_ticker.Stop()
-		for {
-			func() {
-				// defer func() {
-				// 	if e := recover(); e != nil {
-				// 		log.Printf("panic: %v", e)
-				// 	}
-				// }()
-				select {
-				case <-region_ticker.C:
-					if err := region.Refresh(); err != nil {
-						log.Println(err)
-					}
-				case <-instance_ticker.C:
-					for _, errchans := range region.RefreshInstances() {
-						for _, errchan := range errchans {
-							if err := <-errchan; err != nil {
-								fmt.Println(err)
-							}
-						}
-					}
-				}
-				pub.Publish()
-			}()
+	if !offline {
+		start := time.Now()
+		if err := fleet.Refresh(); err != nil {
+			// regions are served with whatever loaded, see /health
+			log.Println(err)
+		}
+		fmt.Println("First refresh in", time.Since(start))
+		for _, region := range fleet.Regions {
+			save(region)
 		}
-	}()
+
+		fleet.Run(*region_interval, *instance_interval, func(region *window.Region) {
+			save(region)
+			pub.Publish(region.Key() + "/")
+		})
+	}
 
 	mux := NewMux(func() {
 		if *dev {
@@ -141,21 +338,52 @@ func main() {
 		if err := templateSet.templates.ExecuteTemplate(w, "index.html", struct {
 			Request *http.Request
 			Region  *window.Region
+			Fleet   *window.Fleet
+			At      string
 		}{
 			Request: req,
-			Region:  region,
+			Region:  selectedRegion(req),
+			Fleet:   fleet,
+			At:      req.URL.Query().Get("at"),
 		}); err != nil {
 			log.Println(err)
 		}
 	})
+	mux.HandleFunc("/_region/", func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(req.URL.Path, "/_region/")
+		if fleet.Region(name) == nil {
+			http.Error(w, fmt.Sprintf("%q region not found", name), http.StatusNotFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "region", Value: name, Path: "/"})
+		http.Redirect(w, req, "/", http.StatusFound)
+	})
 	mux.HandleFunc("/data/", func(w http.ResponseWriter, req *http.Request) {
+		if len(req.URL.Query().Get("at")) > 0 {
+			region, err := regionAt(req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			// snapshots don't change, send once and hold the
+			// connection open so the page doesn't reconnect
+			websocket.Handler(func(ws *websocket.Conn) {
+				defer ws.Close()
+				if err := websocket.Message.Send(ws, render(region, req.URL.Path)); err != nil {
+					return
+				}
+				io.Copy(ioutil.Discard, ws)
+			}).ServeHTTP(w, req)
+			return
+		}
+		key := selectedRegion(req).Key() + req.URL.Path
 		websocket.Handler(func(ws *websocket.Conn) {
 
 			defer ws.Close()
 
 			publish := make(chan string, 1) // so we don't miss it
-			pub.Subscribe(req.URL.Path, publish)
-			defer pub.Unsubscribe(req.URL.Path, publish)
+			pub.Subscribe(key, publish)
+			defer pub.Unsubscribe(key, publish)
 
 			for {
 				if err := websocket.Message.Send(ws, <-publish); err != nil {
@@ -175,13 +403,93 @@ func main() {
 			http.Error(w, "invalid _data path", http.StatusBadRequest)
 			return
 		}
-		if v, exists := region.Items[parts[1]]; exists {
+		region, err := regionAt(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		region.Lock()
+		v, exists := region.Items[parts[1]]
+		region.Unlock()
+		if exists {
 			fmt.Fprint(w, templateSet.Execute("_"+parts[0]+"_data.html", v))
 			return
 		}
 		http.Error(w, fmt.Sprintf("%s/%s not found", parts[0], parts[1]), http.StatusNotFound)
 	})
 
+	// resource change events as server-sent events, starting
+	// with those of the last ?since= (default 1h) for ?region=
+	mux.HandleFunc("/events", func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		since := time.Hour
+		if s := req.URL.Query().Get("since"); len(s) > 0 {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			since = d
+		}
+		region := req.URL.Query().Get("region")
+
+		events := make(chan *window.Event, 256)
+		fleet.Events.Subscribe(events)
+		defer fleet.Events.Unsubscribe(events)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		var last time.Time
+		send := func(e *window.Event) error {
+			if len(region) > 0 && e.Region != region {
+				return nil
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			return err
+		}
+
+		for _, e := range fleet.Events.Since(time.Now().Add(-since)) {
+			if err := send(e); err != nil {
+				return
+			}
+			last = e.Time
+		}
+		flusher.Flush()
+
+		for {
+			select {
+			case e := <-events:
+				// already sent as history
+				if !e.Time.After(last) {
+					continue
+				}
+				if err := send(e); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-req.Context().Done():
+				return
+			}
+		}
+	})
+
+	mux.Handle("/api/v1/", NewAPI(regionAt))
+
+	var tags []string
+	if len(*metrics_tags) > 0 {
+		tags = strings.Split(*metrics_tags, ",")
+	}
+	mux.Handle("/metrics", NewMetrics(fleet, tags))
+
 	mux.Handle("/assets/", http.FileServer(http.Dir("./web/")))
 
 	fmt.Println("ready")
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/emptyinterface/window"
+)
+
+type (
+	// Metrics serves the stats of every region in the prometheus
+	// text format, along with window's own refresh and api health.
+	Metrics struct {
+		fleet *window.Fleet
+		tags  []string
+	}
+)
+
+func NewMetrics(fleet *window.Fleet, tags []string) *Metrics {
+	return &Metrics{fleet: fleet, tags: tags}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	samples := window.APICallSamples()
+	for _, region := range m.fleet.Regions {
+		region.Lock()
+		samples = append(samples, region.Samples(m.tags)...)
+		samples = append(samples, region.HealthSamples()...)
+		region.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := window.WriteSamples(w, samples); err != nil {
+		log.Println("metrics:", err)
+	}
+
+}
@@ -1,6 +1,9 @@
 package main
 
-import "sync"
+import (
+	"strings"
+	"sync"
+)
 
 type (
 	Publisher struct {
@@ -40,10 +43,14 @@ func (p *Publisher) Unsubscribe(path string, node chan string) {
 	}
 }
 
-func (p *Publisher) Publish() {
+// Publish renders and sends all subscribed keys beginning with prefix
+func (p *Publisher) Publish(prefix string) {
 	p.me.Lock()
 	defer p.me.Unlock()
 	for path, nodes := range p.nodes {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
 		data := p.f(path)
 		for node, _ := range nodes {
 			select {
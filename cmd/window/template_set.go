@@ -107,6 +107,8 @@ var (
 		"templates/_subnet.html",
 		"templates/_subnet_sm.html",
 
+		"templates/_health.html",
+
 		"templates/_ami_data.html",
 		"templates/_cloudwatch_alarm_data.html",
 		"templates/_ecc_data.html",
@@ -213,10 +215,18 @@ var (
 		"same": func(a, b interface{}) bool {
 			return reflect.ValueOf(a) == reflect.ValueOf(b)
 		},
-		"humanBytes": func(b int64, precision int) string {
+		"humanBytes": func(b interface{}, precision int) string {
 			const divisor = 1024
 			var i int
-			var n = float64(b)
+			var n float64
+			switch v := reflect.ValueOf(b); v.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n = float64(v.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				n = float64(v.Uint())
+			case reflect.Float32, reflect.Float64:
+				n = v.Float()
+			}
 			for ; n >= divisor; i, n = i+1, n/divisor {
 			}
 			return strings.TrimSuffix(strconv.FormatFloat(n, 'f', precision, 64), ".0") + sizeNames[i]
@@ -276,10 +286,24 @@ var (
 		"seconds": func(n int64) string {
 			return (time.Duration(n) * time.Second).String()
 		},
+		// sparkline draws the history of one of a resource's Metrics
+		"sparkline": func(metrics map[string]*window.Series, name string) template.HTML {
+			points := metrics[name].Sparkline(sparklineWidth, sparklineHeight)
+			if len(points) == 0 {
+				return ""
+			}
+			return template.HTML(fmt.Sprintf(`<svg class="sparkline" width="%d" height="%d"><title>%s</title><polyline points="%s"/></svg>`,
+				sparklineWidth, sparklineHeight, template.HTMLEscapeString(name), points))
+		},
 	}
 	sizeNames []string = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
 )
 
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 20
+)
+
 func commify(v interface{}) string {
 	s := fmt.Sprintf("%v", v)
 	var num, frac string
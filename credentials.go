@@ -0,0 +1,224 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+type (
+	// KeyProvider supplies the ssh keys to offer for an ec2 key pair name
+	KeyProvider interface {
+		Signers(keyName string) ([]ssh.Signer, error)
+	}
+
+	// UserProvider suggests login users for an instance, most likely
+	// first, or none if it can't tell
+	UserProvider interface {
+		Users(inst *Instance) []string
+	}
+
+	// KeyFiles reads key pairs' private keys from pem files, at the path
+	// mapped to the key name or Dir/<name>.pem otherwise
+	KeyFiles struct {
+		Dir   string
+		Paths map[string]string
+	}
+
+	// AgentKeys offers the keys of a running ssh-agent for any key name
+	AgentKeys struct {
+		socket string
+		conn   net.Conn
+		client agent.ExtendedAgent
+		me     sync.Mutex
+	}
+
+	// TagUser takes the login user from the instance tag it names
+	TagUser string
+
+	// AMIUsers suggests the login user of the first entry whose Match is
+	// found in an instance's AMI name, ignoring case
+	AMIUsers []AMIUser
+
+	AMIUser struct {
+		Match string
+		User  string
+	}
+)
+
+const DefaultSSHUserTag = "window:ssh-user"
+
+var (
+	// DefaultSSHUsers are tried when no provider suggests a user
+	DefaultSSHUsers = []string{"ubuntu", "centos", "ec2-user", "admin"}
+
+	// DefaultAMIUsers are the login users of common public AMIs
+	DefaultAMIUsers = AMIUsers{
+		{"bitnami", "bitnami"},
+		{"ubuntu", "ubuntu"},
+		{"debian", "admin"},
+		{"centos", "centos"},
+		{"fedora", "fedora"},
+		{"amzn", "ec2-user"},
+		{"al2023", "ec2-user"},
+		{"rhel", "ec2-user"},
+		{"suse", "ec2-user"},
+	}
+)
+
+// LoadKeyFiles reads a json object of key pair names to private key
+// paths, other key pairs are looked for in dir
+func LoadKeyFiles(dir, path string) (*KeyFiles, error) {
+
+	kf := &KeyFiles{Dir: dir}
+
+	if len(path) > 0 {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &kf.Paths); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for name, p := range kf.Paths {
+			kf.Paths[name] = os.ExpandEnv(p)
+		}
+	}
+
+	return kf, nil
+
+}
+
+func (kf *KeyFiles) Signers(keyName string) ([]ssh.Signer, error) {
+
+	if len(keyName) == 0 {
+		return nil, unreachableError("Key not specified")
+	}
+
+	path, exists := kf.Paths[keyName]
+	if !exists {
+		path = filepath.Join(kf.Dir, keyName+".pem")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, unreachableError("Key file not found")
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, unreachableError("Key file not valid")
+	}
+
+	return []ssh.Signer{signer}, nil
+
+}
+
+// NewAgentKeys returns the keys of the ssh-agent listening on socket,
+// usually $SSH_AUTH_SOCK.  The agent is connected to on first use.
+func NewAgentKeys(socket string) *AgentKeys {
+	return &AgentKeys{socket: socket}
+}
+
+func (a *AgentKeys) Signers(string) ([]ssh.Signer, error) {
+
+	a.me.Lock()
+	defer a.me.Unlock()
+
+	if a.client == nil {
+		conn, err := net.Dial("unix", a.socket)
+		if err != nil {
+			return nil, fmt.Errorf("ssh-agent: %v", err)
+		}
+		a.conn, a.client = conn, agent.NewClient(conn)
+	}
+
+	signers, err := a.client.Signers()
+	if err != nil {
+		// reconnect next time
+		a.conn.Close()
+		a.conn, a.client = nil, nil
+		return nil, fmt.Errorf("ssh-agent: %v", err)
+	}
+
+	return signers, nil
+
+}
+
+func (tag TagUser) Users(inst *Instance) []string {
+	if user := TagOrDefault(inst.Tags, string(tag)); len(user) > 0 {
+		return []string{user}
+	}
+	return nil
+}
+
+func (users AMIUsers) Users(inst *Instance) []string {
+	if strings.EqualFold(inst.Platform, "windows") {
+		return []string{"Administrator"}
+	}
+	if inst.AMI == nil {
+		return nil
+	}
+	name := strings.ToLower(inst.AMI.Name)
+	for _, u := range users {
+		if strings.Contains(name, strings.ToLower(u.Match)) {
+			return []string{u.User}
+		}
+	}
+	return nil
+}
+
+// signers collects the keys of every provider for a key pair.  Errors
+// only matter if no provider has any keys.
+func signers(providers []KeyProvider, keyName string) ([]ssh.Signer, error) {
+
+	var (
+		all   []ssh.Signer
+		first error
+	)
+
+	for _, provider := range providers {
+		s, err := provider.Signers(keyName)
+		if err != nil && first == nil {
+			first = err
+		}
+		all = append(all, s...)
+	}
+
+	if len(all) == 0 {
+		if first == nil {
+			first = unreachableError("No keys found")
+		}
+		return nil, first
+	}
+
+	return all, nil
+
+}
+
+// keys returns the poller's key providers, or the region's key files
+func (p *InstancePoller) keys(region *Region) []KeyProvider {
+	if len(p.Keys) > 0 {
+		return p.Keys
+	}
+	return []KeyProvider{&KeyFiles{Dir: region.sshKeyPath}}
+}
+
+// users returns the login users suggested by the first provider
+// that has any, or DefaultSSHUsers
+func (p *InstancePoller) users(inst *Instance) []string {
+	for _, provider := range p.Users {
+		if users := provider.Users(inst); len(users) > 0 {
+			return users
+		}
+	}
+	return DefaultSSHUsers
+}
@@ -0,0 +1,151 @@
+package window
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func writePrivateKey(t *testing.T, path string) ed25519.PrivateKey {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestKeyFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writePrivateKey(t, filepath.Join(dir, "web.pem"))
+	writePrivateKey(t, filepath.Join(dir, "id_ops"))
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad.pem"), []byte("nope"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "keys.json"), []byte(`{"ops": "`+filepath.Join(dir, "id_ops")+`"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	kf, err := LoadKeyFiles(dir, filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name string
+		err  error
+	}{
+		{"web", nil},
+		{"ops", nil},
+		{"", unreachableError("Key not specified")},
+		{"missing", unreachableError("Key file not found")},
+		{"bad", unreachableError("Key file not valid")},
+	} {
+		signers, err := kf.Signers(test.name)
+		if err != test.err {
+			t.Errorf("%q: Expected error %v, got %v", test.name, test.err, err)
+		}
+		if test.err == nil && len(signers) != 1 {
+			t.Errorf("%q: Expected 1 signer, got %d", test.name, len(signers))
+		}
+	}
+
+}
+
+func TestAgentKeys(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyring := agent.NewKeyring()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	keys := []KeyProvider{&KeyFiles{Dir: dir}, NewAgentKeys(sock)}
+
+	// the agent's keys make up for the missing key file
+	all, err := signers(keys, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("Expected 1 signer, got %d", len(all))
+	}
+
+	if _, err := signers([]KeyProvider{NewAgentKeys(filepath.Join(dir, "none.sock"))}, "web"); err == nil {
+		t.Error("Expected error without an agent")
+	}
+
+}
+
+func TestInstancePollerUsers(t *testing.T) {
+
+	p := NewInstancePoller(1)
+
+	for _, test := range []struct {
+		inst  *Instance
+		users []string
+	}{
+		{&Instance{}, DefaultSSHUsers},
+		{&Instance{AMI: &AMI{Name: "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server"}}, []string{"ubuntu"}},
+		{&Instance{AMI: &AMI{Name: "amzn2-ami-hvm-2.0.20230404.1-x86_64-gp2"}}, []string{"ec2-user"}},
+		{&Instance{AMI: &AMI{Name: "debian-12-amd64"}}, []string{"admin"}},
+		{&Instance{AMI: &AMI{Name: "custom-image"}}, DefaultSSHUsers},
+		{&Instance{Platform: "windows"}, []string{"Administrator"}},
+		{&Instance{
+			AMI:  &AMI{Name: "debian-12-amd64"},
+			Tags: []*ec2.Tag{{Key: aws.String(DefaultSSHUserTag), Value: aws.String("deploy")}},
+		}, []string{"deploy"}},
+	} {
+		if users := p.users(test.inst); !reflect.DeepEqual(users, test.users) {
+			t.Errorf("Expected %v, got %v", test.users, users)
+		}
+	}
+
+}
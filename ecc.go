@@ -122,6 +122,7 @@ type (
 
 		Name              string
 		Id                string
+		AccountId         string
 		State             string
 		Region            *Region
 		AvailabilityZones []*AvailabilityZone
@@ -130,7 +131,8 @@ type (
 		SecurityGroups    []*SecurityGroup
 		CloudWatchAlarms  []*CloudWatchAlarm
 
-		Stats []*ECCNodeStats
+		Stats   []*ECCNodeStats
+		Metrics map[string]*Series
 	}
 
 	ElasticCacheClusterByNameAsc []*ElasticCacheCluster
@@ -144,7 +146,7 @@ func (a ElasticCacheClusterByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadCacheClusters(input *elasticache.DescribeCacheClustersInput) (map[string]*ElasticCacheCluster, error) {
+func LoadCacheClusters(client ElastiCacheAPI, input *elasticache.DescribeCacheClustersInput) (map[string]*ElasticCacheCluster, error) {
 
 	if input == nil {
 		input = &elasticache.DescribeCacheClustersInput{}
@@ -153,7 +155,7 @@ func LoadCacheClusters(input *elasticache.DescribeCacheClustersInput) (map[strin
 
 	eccs := map[string]*ElasticCacheCluster{}
 
-	if err := ECClient.DescribeCacheClustersPages(input, func(page *elasticache.DescribeCacheClustersOutput, _ bool) bool {
+	if err := client.DescribeCacheClustersPages(input, func(page *elasticache.DescribeCacheClustersOutput, _ bool) bool {
 		for _, cc := range page.CacheClusters {
 			ecc := &ElasticCacheCluster{
 				CacheClusterCreateTime:     aws.TimeValue(cc.CacheClusterCreateTime),
@@ -224,23 +226,21 @@ func (ecc *ElasticCacheCluster) MonthlyCost() float64 {
 	return 0
 }
 
-func (ecc *ElasticCacheCluster) Poll() []chan error {
+func (ecc *ElasticCacheCluster) Poll(metrics *MetricFetcher) []chan error {
 
-	var errs []chan error
 	ecc.Stats = nil
 
 	for _, node := range ecc.CacheNodes {
 		stats := NewECCNodeStats(ecc, node)
 		ecc.Stats = append(ecc.Stats, stats)
 		for _, m := range ECCMetrics {
-			m := m
-			errs = append(errs, ecc.Region.Throttle.do(ecc.Name+" ECC METRICS POLL", func() error {
-				return m.RunFor(stats)
-			}))
+			if q := m.Query(stats); q != nil {
+				metrics.Add(q)
+			}
 		}
 	}
 
-	return errs
+	return nil
 
 }
 
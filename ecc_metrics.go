@@ -452,17 +452,16 @@ func NewECCNodeStats(ecc *ElasticCacheCluster, node *elasticache.CacheNode) *ECC
 	return stats
 }
 
-func (m *eccmetric) RunFor(stats *ECCNodeStats) error {
+// Query returns nil if the metric does not apply to the node's engine
+func (m *eccmetric) Query(stats *ECCNodeStats) *MetricQuery {
 
 	if len(m.engine) > 0 && m.engine != stats.Cluster.Engine {
 		return nil
 	}
 
-	resp, err := CloudWatchClient.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
-		StartTime: aws.Time(time.Now().Add(-PeriodInMinutes * time.Minute)),
-		EndTime:   aws.Time(time.Now()),
-		Period:    aws.Int64(PeriodInMinutes * 60),
-		Namespace: aws.String("AWS/ElastiCache"),
+	return &MetricQuery{
+		Namespace: "AWS/ElastiCache",
+		Name:      m.name,
 		Dimensions: []*cloudwatch.Dimension{
 			{
 				Name:  aws.String("CacheClusterId"),
@@ -473,14 +472,11 @@ func (m *eccmetric) RunFor(stats *ECCNodeStats) error {
 				Value: stats.Node.CacheNodeId,
 			},
 		},
-		MetricName: m.name,
 		Statistics: m.statistics,
 		Unit:       m.unit, // fuck this in teh face
-	})
-	if err == nil && len(resp.Datapoints) > 0 {
-		m.processor(stats, resp.Datapoints[0])
+		processor: func(point *cloudwatch.Datapoint) {
+			m.processor(stats, point)
+		},
 	}
 
-	return err
-
 }
@@ -74,6 +74,7 @@ type (
 
 		Name                string
 		Id                  string
+		AccountId           string
 		State               string
 		Region              *Region
 		VPC                 *VPC
@@ -86,7 +87,8 @@ type (
 		SourceSecurityGroup *SecurityGroup
 		CloudWatchAlarms    []*CloudWatchAlarm
 
-		Stats *ELBStats
+		Stats   *ELBStats
+		Metrics map[string]*Series
 	}
 
 	ELBPolicies struct {
@@ -111,11 +113,11 @@ func (a ELBByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadELBs(input *elb.DescribeLoadBalancersInput) (map[string]*ELB, error) {
+func LoadELBs(client ELBAPI, input *elb.DescribeLoadBalancersInput) (map[string]*ELB, error) {
 
 	elbs := map[string]*ELB{}
 
-	if err := ELBClient.DescribeLoadBalancersPages(input, func(page *elb.DescribeLoadBalancersOutput, _ bool) bool {
+	if err := client.DescribeLoadBalancersPages(input, func(page *elb.DescribeLoadBalancersOutput, _ bool) bool {
 		for _, awselb := range page.LoadBalancerDescriptions {
 			elb := &ELB{
 				AvailabilityZoneNames:     aws.StringValueSlice(awselb.AvailabilityZones),
@@ -150,19 +152,15 @@ func LoadELBs(input *elb.DescribeLoadBalancersInput) (map[string]*ELB, error) {
 
 }
 
-func (elb *ELB) Poll() []chan error {
+func (elb *ELB) Poll(metrics *MetricFetcher) []chan error {
 
-	var errs []chan error
 	elb.Stats = &ELBStats{}
 
 	for _, m := range ELBMetrics {
-		m := m
-		errs = append(errs, elb.Region.Throttle.do(elb.Name+" METRICS POLL", func() error {
-			return m.RunFor(elb)
-		}))
+		metrics.Add(m.Query(elb))
 	}
 
-	return errs
+	return nil
 
 }
 
@@ -144,25 +144,18 @@ var (
 	}
 )
 
-func (m *elbmetric) RunFor(elb *ELB) error {
-
-	resp, err := CloudWatchClient.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
-		StartTime: aws.Time(time.Now().Add(-PeriodInMinutes * time.Minute)),
-		EndTime:   aws.Time(time.Now()),
-		Period:    aws.Int64(PeriodInMinutes * 60),
-		Namespace: aws.String("AWS/ELB"),
+func (m *elbmetric) Query(elb *ELB) *MetricQuery {
+	return &MetricQuery{
+		Namespace: "AWS/ELB",
+		Name:      m.name,
 		Dimensions: []*cloudwatch.Dimension{{
 			Name:  aws.String("LoadBalancerName"),
 			Value: aws.String(elb.Name),
 		}},
-		MetricName: m.name,
 		Statistics: m.statistics,
 		Unit:       m.unit, // fuck this in teh face
-	})
-	if err == nil && len(resp.Datapoints) > 0 {
-		m.processor(elb, resp.Datapoints[0])
+		processor: func(point *cloudwatch.Datapoint) {
+			m.processor(elb, point)
+		},
 	}
-
-	return err
-
 }
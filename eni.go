@@ -65,6 +65,7 @@ type (
 
 		Name           string
 		Id             string
+		AccountId      string
 		State          string
 		VPC            *VPC
 		SecurityGroups []*SecurityGroup
@@ -79,9 +80,9 @@ func (a ENIByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadENIs(input *ec2.DescribeNetworkInterfacesInput) (map[string]*ENI, error) {
+func LoadENIs(client EC2API, input *ec2.DescribeNetworkInterfacesInput) (map[string]*ENI, error) {
 
-	resp, err := EC2Client.DescribeNetworkInterfaces(input)
+	resp, err := client.DescribeNetworkInterfaces(input)
 	if err != nil {
 		return nil, err
 	}
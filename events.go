@@ -0,0 +1,255 @@
+package window
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type (
+	EventType string
+
+	// Event is a change to a resource found between two refreshes of a region
+	Event struct {
+		Time   time.Time `json:"time"`
+		Type   EventType `json:"type"`
+		Region string    `json:"region"`
+		Key    string    `json:"key"`
+		Kind   string    `json:"kind"`
+		Name   string    `json:"name"`
+		Old    string    `json:"old,omitempty"`
+		New    string    `json:"new,omitempty"`
+	}
+
+	// EventLog keeps the events of the last Retention and
+	// fans newly published events out to subscribers
+	EventLog struct {
+		Retention time.Duration
+
+		events      []*Event
+		subscribers map[chan *Event]struct{}
+		me          sync.Mutex
+	}
+
+	EventByKeyAsc []*Event
+)
+
+const (
+	EventAdded        EventType = "added"
+	EventRemoved      EventType = "removed"
+	EventStateChanged EventType = "state"
+	EventTagsChanged  EventType = "tags"
+	EventRulesChanged EventType = "rules"
+)
+
+func NewEventLog(retention time.Duration) *EventLog {
+	return &EventLog{
+		Retention:   retention,
+		subscribers: map[chan *Event]struct{}{},
+	}
+}
+
+// Subscribe sends all events published after subscribing to ch.
+// Events are dropped rather than block when ch is full.
+func (l *EventLog) Subscribe(ch chan *Event) {
+	l.me.Lock()
+	defer l.me.Unlock()
+	l.subscribers[ch] = struct{}{}
+}
+
+func (l *EventLog) Unsubscribe(ch chan *Event) {
+	l.me.Lock()
+	defer l.me.Unlock()
+	delete(l.subscribers, ch)
+}
+
+func (l *EventLog) Publish(events ...*Event) {
+	l.me.Lock()
+	defer l.me.Unlock()
+	l.events = append(l.events, events...)
+	cutoff := time.Now().Add(-l.Retention)
+	for len(l.events) > 0 && l.events[0].Time.Before(cutoff) {
+		l.events = l.events[1:]
+	}
+	for _, e := range events {
+		for ch, _ := range l.subscribers {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+// Since returns the retained events that occurred after t, oldest first
+func (l *EventLog) Since(t time.Time) []*Event {
+	l.me.Lock()
+	defer l.me.Unlock()
+	i := sort.Search(len(l.events), func(i int) bool { return l.events[i].Time.After(t) })
+	return append([]*Event(nil), l.events[i:]...)
+}
+
+// Diff compares the Items of two refreshes of a region and returns
+// an event for each resource added, removed or changed.
+func Diff(region string, prev, next map[string]interface{}, now time.Time) []*Event {
+
+	var events []*Event
+
+	event := func(typ EventType, key string, v interface{}, before, after string) {
+		events = append(events, &Event{
+			Time:   now,
+			Type:   typ,
+			Region: region,
+			Key:    key,
+			Kind:   resourceKind(v),
+			Name:   resourceString(v, "Name"),
+			Old:    before,
+			New:    after,
+		})
+	}
+
+	for key, v := range next {
+		p, exists := prev[key]
+		if !exists {
+			event(EventAdded, key, v, "", "")
+			continue
+		}
+		if before, after := resourceString(p, "State"), resourceString(v, "State"); before != after {
+			event(EventStateChanged, key, v, before, after)
+		}
+		if before, after := resourceTags(p), resourceTags(v); before != after {
+			event(EventTagsChanged, key, v, before, after)
+		}
+		if psg, ok := p.(*SecurityGroup); ok {
+			if removed, added := diffStrings(securityGroupRules(psg), securityGroupRules(v.(*SecurityGroup))); len(removed) > 0 || len(added) > 0 {
+				event(EventRulesChanged, key, v, strings.Join(removed, ", "), strings.Join(added, ", "))
+			}
+		}
+	}
+
+	for key, v := range prev {
+		if _, exists := next[key]; !exists {
+			event(EventRemoved, key, v, "", "")
+		}
+	}
+
+	sort.Sort(EventByKeyAsc(events))
+
+	return events
+
+}
+
+func (a EventByKeyAsc) Len() int      { return len(a) }
+func (a EventByKeyAsc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a EventByKeyAsc) Less(i, j int) bool {
+	if a[i].Key == a[j].Key {
+		return a[i].Type < a[j].Type
+	}
+	return a[i].Key < a[j].Key
+}
+
+func resourceKind(v interface{}) string {
+	return reflect.Indirect(reflect.ValueOf(v)).Type().Name()
+}
+
+// resourceString returns the named string field of a resource, if it has one
+func resourceString(v interface{}, name string) string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return ""
+	}
+	if field := val.FieldByName(name); field.IsValid() && field.Kind() == reflect.String {
+		return field.String()
+	}
+	return ""
+}
+
+// resourceTags returns the Tags of a resource as sorted key=value pairs.
+// ec2 and autoscaling tags both have Key and Value fields.
+func resourceTags(v interface{}) string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return ""
+	}
+	tags := val.FieldByName("Tags")
+	if !tags.IsValid() || tags.Kind() != reflect.Slice {
+		return ""
+	}
+	var pairs []string
+	for i := 0; i < tags.Len(); i++ {
+		tag := reflect.Indirect(tags.Index(i))
+		if tag.Kind() != reflect.Struct {
+			continue
+		}
+		key, value := tag.FieldByName("Key"), tag.FieldByName("Value")
+		if !key.IsValid() || !value.IsValid() {
+			continue
+		}
+		k, _ := key.Interface().(*string)
+		v, _ := value.Interface().(*string)
+		pairs = append(pairs, aws.StringValue(k)+"="+aws.StringValue(v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func securityGroupRules(sg *SecurityGroup) []string {
+	var rules []string
+	for _, dir := range []struct {
+		name  string
+		perms []*ec2.IpPermission
+	}{
+		{"in", sg.IpPermissions},
+		{"out", sg.IpPermissionsEgress},
+	} {
+		for _, perm := range dir.perms {
+			ports := aws.StringValue(perm.IpProtocol)
+			if perm.FromPort != nil && perm.ToPort != nil {
+				ports = fmt.Sprintf("%s %d-%d", ports, *perm.FromPort, *perm.ToPort)
+			}
+			for _, r := range perm.IpRanges {
+				rules = append(rules, fmt.Sprintf("%s %s %s", dir.name, ports, aws.StringValue(r.CidrIp)))
+			}
+			for _, r := range perm.Ipv6Ranges {
+				rules = append(rules, fmt.Sprintf("%s %s %s", dir.name, ports, aws.StringValue(r.CidrIpv6)))
+			}
+			for _, pair := range perm.UserIdGroupPairs {
+				rules = append(rules, fmt.Sprintf("%s %s %s", dir.name, ports, aws.StringValue(pair.GroupId)))
+			}
+			for _, pl := range perm.PrefixListIds {
+				rules = append(rules, fmt.Sprintf("%s %s %s", dir.name, ports, aws.StringValue(pl.PrefixListId)))
+			}
+		}
+	}
+	return rules
+}
+
+// diffStrings returns the sorted strings only in a and only in b
+func diffStrings(a, b []string) (removed, added []string) {
+	as, bs := map[string]struct{}{}, map[string]struct{}{}
+	for _, s := range a {
+		as[s] = struct{}{}
+	}
+	for _, s := range b {
+		bs[s] = struct{}{}
+	}
+	for s, _ := range as {
+		if _, exists := bs[s]; !exists {
+			removed = append(removed, s)
+		}
+	}
+	for s, _ := range bs {
+		if _, exists := as[s]; !exists {
+			added = append(added, s)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	return
+}
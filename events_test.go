@@ -0,0 +1,119 @@
+package window
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestDiff(t *testing.T) {
+
+	now := time.Now()
+
+	prev := map[string]interface{}{
+		"a/inst:i-1": &Instance{Name: "web", State: "running"},
+		"a/inst:i-2": &Instance{Name: "old", State: "running"},
+		"a/vpc-1":    &VPC{Name: "main", Tags: []*ec2.Tag{{Key: aws.String("env"), Value: aws.String("dev")}}},
+		"a/sg-1": &SecurityGroup{Name: "web", IpPermissions: []*ec2.IpPermission{
+			{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(80), ToPort: aws.Int64(80), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		}},
+	}
+	next := map[string]interface{}{
+		"a/inst:i-1": &Instance{Name: "web", State: "stopped"},
+		"a/inst:i-3": &Instance{Name: "new", State: "pending"},
+		"a/vpc-1":    &VPC{Name: "main", Tags: []*ec2.Tag{{Key: aws.String("env"), Value: aws.String("prod")}}},
+		"a/sg-1": &SecurityGroup{Name: "web", IpPermissions: []*ec2.IpPermission{
+			{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(443), ToPort: aws.Int64(443), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		}},
+	}
+
+	expected := []*Event{
+		{Time: now, Type: EventStateChanged, Region: "r", Key: "a/inst:i-1", Kind: "Instance", Name: "web", Old: "running", New: "stopped"},
+		{Time: now, Type: EventRemoved, Region: "r", Key: "a/inst:i-2", Kind: "Instance", Name: "old"},
+		{Time: now, Type: EventAdded, Region: "r", Key: "a/inst:i-3", Kind: "Instance", Name: "new"},
+		{Time: now, Type: EventRulesChanged, Region: "r", Key: "a/sg-1", Kind: "SecurityGroup", Name: "web", Old: "in tcp 80-80 0.0.0.0/0", New: "in tcp 443-443 0.0.0.0/0"},
+		{Time: now, Type: EventTagsChanged, Region: "r", Key: "a/vpc-1", Kind: "VPC", Name: "main", Old: "env=dev", New: "env=prod"},
+	}
+
+	events := Diff("r", prev, next, now)
+
+	if !reflect.DeepEqual(events, expected) {
+		for _, e := range events {
+			t.Logf("%+v", e)
+		}
+		t.Error("unexpected events")
+	}
+
+	if events := Diff("r", next, next, now); len(events) != 0 {
+		t.Errorf("Expected no events for unchanged items, got %d", len(events))
+	}
+
+}
+
+func TestEventLog(t *testing.T) {
+
+	log := NewEventLog(time.Hour)
+
+	events := make(chan *Event, 10)
+	log.Subscribe(events)
+
+	now := time.Now()
+	expired := &Event{Time: now.Add(-2 * time.Hour), Key: "expired"}
+	older := &Event{Time: now.Add(-30 * time.Minute), Key: "older"}
+	newer := &Event{Time: now, Key: "newer"}
+
+	log.Publish(expired, older)
+	log.Publish(newer)
+
+	if since := log.Since(now.Add(-2 * time.Hour)); !reflect.DeepEqual(since, []*Event{older, newer}) {
+		t.Errorf("Expected expired event to be dropped, got %v", since)
+	}
+	if since := log.Since(now.Add(-time.Minute)); !reflect.DeepEqual(since, []*Event{newer}) {
+		t.Errorf("Expected only newer event, got %v", since)
+	}
+
+	if len(events) != 3 {
+		t.Errorf("Expected 3 events sent to subscriber, got %d", len(events))
+	}
+
+	log.Unsubscribe(events)
+	log.Publish(&Event{Time: now})
+	if len(events) != 3 {
+		t.Error("event sent after unsubscribe")
+	}
+
+}
+
+func TestRegionRefreshPublishesEvents(t *testing.T) {
+
+	region := newFakeRegion()
+	region.Events = NewEventLog(time.Hour)
+	defer region.Throttle.stop()
+
+	events := make(chan *Event, 10)
+	region.Events.Subscribe(events)
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no events from first refresh, got %d", len(events))
+	}
+
+	fake := region.Clients.EC2.(*fakeEC2)
+	fake.Reservations[0].Instances[1].State = &ec2.InstanceState{Name: aws.String("running")}
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if e := <-events; e.Type != EventStateChanged || e.Key != ItemKey(testAccountId, "inst:i-2") || e.Old != "stopped" || e.New != "running" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+}
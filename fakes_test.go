@@ -0,0 +1,219 @@
+package window
+
+import (
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elasticache"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// in-memory implementations of the Clients interfaces.
+// each returns the configured data, or an empty response.
+
+type (
+	fakeEC2 struct {
+		AvailabilityZones     []*ec2.AvailabilityZone
+		CustomerGateways      []*ec2.CustomerGateway
+		Images                []*ec2.Image
+		Reservations          []*ec2.Reservation
+		InternetGateways      []*ec2.InternetGateway
+		NatGateways           []*ec2.NatGateway
+		NetworkAcls           []*ec2.NetworkAcl
+		NetworkInterfaces     []*ec2.NetworkInterface
+		RouteTables           []*ec2.RouteTable
+		SecurityGroups        []*ec2.SecurityGroup
+		Subnets               []*ec2.Subnet
+		VpcEndpoints          []*ec2.VpcEndpoint
+		VpcPeeringConnections []*ec2.VpcPeeringConnection
+		Vpcs                  []*ec2.Vpc
+		VpnConnections        []*ec2.VpnConnection
+		VpnGateways           []*ec2.VpnGateway
+		// keyed by instance id
+		ConsoleOutputs map[string]string
+	}
+	fakeELB struct {
+		LoadBalancerDescriptions []*elb.LoadBalancerDescription
+	}
+	fakeAutoScaling struct {
+		AutoScalingGroups []*autoscaling.Group
+	}
+	fakeElastiCache struct {
+		CacheClusters []*elasticache.CacheCluster
+	}
+	fakeRDS struct {
+		DBInstances []*rds.DBInstance
+	}
+	fakeCloudWatch struct {
+		MetricAlarms []*cloudwatch.MetricAlarm
+		// keyed by metric name:stat, e.g. RequestCount:Sum
+		Values map[string]float64
+		// number of GetMetricData requests and their sizes
+		MetricDataCalls []int
+		me              sync.Mutex
+	}
+	fakeSQS struct {
+		// keyed by queue url
+		Queues map[string]map[string]*string
+	}
+	fakeSNS struct {
+		// keyed by topic arn
+		Topics        map[string]map[string]*string
+		Subscriptions []*sns.Subscription
+	}
+	fakeLambda struct {
+		Functions []*lambda.FunctionConfiguration
+	}
+	fakeSTS struct {
+		Account *string
+	}
+)
+
+func (f *fakeEC2) DescribeAvailabilityZones(*ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	return &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: f.AvailabilityZones}, nil
+}
+func (f *fakeEC2) DescribeCustomerGateways(*ec2.DescribeCustomerGatewaysInput) (*ec2.DescribeCustomerGatewaysOutput, error) {
+	return &ec2.DescribeCustomerGatewaysOutput{CustomerGateways: f.CustomerGateways}, nil
+}
+func (f *fakeEC2) DescribeImages(*ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	return &ec2.DescribeImagesOutput{Images: f.Images}, nil
+}
+func (f *fakeEC2) DescribeInstancesPages(_ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	fn(&ec2.DescribeInstancesOutput{Reservations: f.Reservations}, true)
+	return nil
+}
+func (f *fakeEC2) DescribeInternetGateways(*ec2.DescribeInternetGatewaysInput) (*ec2.DescribeInternetGatewaysOutput, error) {
+	return &ec2.DescribeInternetGatewaysOutput{InternetGateways: f.InternetGateways}, nil
+}
+func (f *fakeEC2) DescribeNatGateways(*ec2.DescribeNatGatewaysInput) (*ec2.DescribeNatGatewaysOutput, error) {
+	return &ec2.DescribeNatGatewaysOutput{NatGateways: f.NatGateways}, nil
+}
+func (f *fakeEC2) DescribeNetworkAcls(*ec2.DescribeNetworkAclsInput) (*ec2.DescribeNetworkAclsOutput, error) {
+	return &ec2.DescribeNetworkAclsOutput{NetworkAcls: f.NetworkAcls}, nil
+}
+func (f *fakeEC2) DescribeNetworkInterfaces(*ec2.DescribeNetworkInterfacesInput) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: f.NetworkInterfaces}, nil
+}
+func (f *fakeEC2) DescribeRouteTables(*ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	return &ec2.DescribeRouteTablesOutput{RouteTables: f.RouteTables}, nil
+}
+func (f *fakeEC2) DescribeSecurityGroups(*ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.SecurityGroups}, nil
+}
+func (f *fakeEC2) DescribeSubnets(*ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: f.Subnets}, nil
+}
+func (f *fakeEC2) DescribeVpcEndpoints(*ec2.DescribeVpcEndpointsInput) (*ec2.DescribeVpcEndpointsOutput, error) {
+	return &ec2.DescribeVpcEndpointsOutput{VpcEndpoints: f.VpcEndpoints}, nil
+}
+func (f *fakeEC2) DescribeVpcPeeringConnections(*ec2.DescribeVpcPeeringConnectionsInput) (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	return &ec2.DescribeVpcPeeringConnectionsOutput{VpcPeeringConnections: f.VpcPeeringConnections}, nil
+}
+func (f *fakeEC2) DescribeVpcs(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{Vpcs: f.Vpcs}, nil
+}
+func (f *fakeEC2) DescribeVpnConnections(*ec2.DescribeVpnConnectionsInput) (*ec2.DescribeVpnConnectionsOutput, error) {
+	return &ec2.DescribeVpnConnectionsOutput{VpnConnections: f.VpnConnections}, nil
+}
+func (f *fakeEC2) DescribeVpnGateways(*ec2.DescribeVpnGatewaysInput) (*ec2.DescribeVpnGatewaysOutput, error) {
+	return &ec2.DescribeVpnGatewaysOutput{VpnGateways: f.VpnGateways}, nil
+}
+func (f *fakeEC2) GetConsoleOutput(input *ec2.GetConsoleOutputInput) (*ec2.GetConsoleOutputOutput, error) {
+	output := base64.StdEncoding.EncodeToString([]byte(f.ConsoleOutputs[aws.StringValue(input.InstanceId)]))
+	return &ec2.GetConsoleOutputOutput{InstanceId: input.InstanceId, Output: aws.String(output)}, nil
+}
+
+func (f *fakeELB) DescribeLoadBalancersPages(_ *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool) error {
+	fn(&elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: f.LoadBalancerDescriptions}, true)
+	return nil
+}
+
+func (f *fakeAutoScaling) DescribeAutoScalingGroupsPages(_ *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+	fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: f.AutoScalingGroups}, true)
+	return nil
+}
+
+func (f *fakeElastiCache) DescribeCacheClustersPages(_ *elasticache.DescribeCacheClustersInput, fn func(*elasticache.DescribeCacheClustersOutput, bool) bool) error {
+	fn(&elasticache.DescribeCacheClustersOutput{CacheClusters: f.CacheClusters}, true)
+	return nil
+}
+
+func (f *fakeRDS) DescribeDBInstancesPages(_ *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool) error {
+	fn(&rds.DescribeDBInstancesOutput{DBInstances: f.DBInstances}, true)
+	return nil
+}
+func (f *fakeRDS) DescribeDBLogFiles(*rds.DescribeDBLogFilesInput) (*rds.DescribeDBLogFilesOutput, error) {
+	return &rds.DescribeDBLogFilesOutput{}, nil
+}
+func (f *fakeRDS) DownloadDBLogFilePortion(*rds.DownloadDBLogFilePortionInput) (*rds.DownloadDBLogFilePortionOutput, error) {
+	return &rds.DownloadDBLogFilePortionOutput{}, nil
+}
+
+func (f *fakeCloudWatch) DescribeAlarmsPages(_ *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+	fn(&cloudwatch.DescribeAlarmsOutput{MetricAlarms: f.MetricAlarms}, true)
+	return nil
+}
+func (f *fakeCloudWatch) GetMetricDataPages(input *cloudwatch.GetMetricDataInput, fn func(*cloudwatch.GetMetricDataOutput, bool) bool) error {
+	f.me.Lock()
+	f.MetricDataCalls = append(f.MetricDataCalls, len(input.MetricDataQueries))
+	f.me.Unlock()
+	resp := &cloudwatch.GetMetricDataOutput{}
+	for _, q := range input.MetricDataQueries {
+		result := &cloudwatch.MetricDataResult{Id: q.Id}
+		if v, exists := f.Values[aws.StringValue(q.MetricStat.Metric.MetricName)+":"+aws.StringValue(q.MetricStat.Stat)]; exists {
+			result.Timestamps = []*time.Time{input.EndTime}
+			result.Values = []*float64{aws.Float64(v)}
+		}
+		resp.MetricDataResults = append(resp.MetricDataResults, result)
+	}
+	fn(resp, true)
+	return nil
+}
+
+func (f *fakeSQS) ListQueues(*sqs.ListQueuesInput) (*sqs.ListQueuesOutput, error) {
+	resp := &sqs.ListQueuesOutput{}
+	for url := range f.Queues {
+		url := url
+		resp.QueueUrls = append(resp.QueueUrls, &url)
+	}
+	return resp, nil
+}
+func (f *fakeSQS) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	return &sqs.GetQueueAttributesOutput{Attributes: f.Queues[*input.QueueUrl]}, nil
+}
+
+func (f *fakeSNS) ListTopicsPages(_ *sns.ListTopicsInput, fn func(*sns.ListTopicsOutput, bool) bool) error {
+	resp := &sns.ListTopicsOutput{}
+	for arn := range f.Topics {
+		arn := arn
+		resp.Topics = append(resp.Topics, &sns.Topic{TopicArn: &arn})
+	}
+	fn(resp, true)
+	return nil
+}
+func (f *fakeSNS) GetTopicAttributes(input *sns.GetTopicAttributesInput) (*sns.GetTopicAttributesOutput, error) {
+	return &sns.GetTopicAttributesOutput{Attributes: f.Topics[*input.TopicArn]}, nil
+}
+func (f *fakeSNS) ListSubscriptionsPages(_ *sns.ListSubscriptionsInput, fn func(*sns.ListSubscriptionsOutput, bool) bool) error {
+	fn(&sns.ListSubscriptionsOutput{Subscriptions: f.Subscriptions}, true)
+	return nil
+}
+
+func (f *fakeLambda) ListFunctionsPages(_ *lambda.ListFunctionsInput, fn func(*lambda.ListFunctionsOutput, bool) bool) error {
+	fn(&lambda.ListFunctionsOutput{Functions: f.Functions}, true)
+	return nil
+}
+
+func (f *fakeSTS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Account: f.Account}, nil
+}
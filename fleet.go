@@ -0,0 +1,145 @@
+package window
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/emptyinterface/window/pricing"
+)
+
+type (
+	// Fleet is the set of account regions served by a single window process
+	Fleet struct {
+		Regions []*Region
+
+		// changes to resources in all regions
+		Events *EventLog
+	}
+)
+
+// how long resource change events are kept
+const EventRetention = 24 * time.Hour
+
+// NewFleet creates a Region for each of the account's regions,
+// or the default regions if the account does not specify any.
+func NewFleet(accounts []*Account, regions []string) (*Fleet, error) {
+	f := &Fleet{Events: NewEventLog(EventRetention)}
+	table, err := pricing.LoadTable()
+	if err != nil {
+		log.Println("unable to load pricing table:", err)
+	}
+	for _, account := range accounts {
+		names := account.Regions
+		if len(names) == 0 {
+			names = regions
+		}
+		for _, name := range names {
+			r, err := newPricedRegion(account, name, table)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", account.Name, err)
+			}
+			r.Events = f.Events
+			f.Regions = append(f.Regions, r)
+		}
+	}
+	return f, nil
+}
+
+// NewSnapshotFleet serves regions read from snapshot files.
+// The regions have no clients and must not be refreshed.
+func NewSnapshotFleet(paths []string) (*Fleet, error) {
+	f := &Fleet{Events: NewEventLog(EventRetention)}
+	for _, path := range paths {
+		r, err := ReadSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		f.Regions = append(f.Regions, r)
+	}
+	return f, nil
+}
+
+// Region looks up a region by its Key
+func (f *Fleet) Region(key string) *Region {
+	for _, r := range f.Regions {
+		if r.Key() == key {
+			return r
+		}
+	}
+	return nil
+}
+
+// Refresh refreshes all regions concurrently and returns the first error
+func (f *Fleet) Refresh() error {
+	errs := make(chan error, len(f.Regions))
+	for _, r := range f.Regions {
+		go func(r *Region) {
+			if err := r.Refresh(); err != nil {
+				errs <- fmt.Errorf("%s: %v", r.Key(), err)
+				return
+			}
+			errs <- nil
+		}(r)
+	}
+	var first error
+	for range f.Regions {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Run starts a refresh loop and an instance poller for each region.
+// refreshed is called after each region refresh or round of instance
+// polls completes.
+func (f *Fleet) Run(region_interval, instance_interval time.Duration, refreshed func(*Region)) {
+	for _, r := range f.Regions {
+		go func(region *Region) {
+			region_ticker := time.NewTicker(region_interval)
+			defer region_ticker.Stop()
+			for range region_ticker.C {
+				if err := region.Refresh(); err != nil {
+					log.Println(region.Key(), err)
+				}
+				refreshed(region)
+			}
+		}(r)
+		go func(region *Region) {
+			region.Poller.Run(region, instance_interval, func() {
+				refreshed(region)
+			})
+		}(r)
+	}
+}
+
+func (f *Fleet) InstanceCount() int {
+	var total int
+	for _, r := range f.Regions {
+		r.Lock()
+		total += len(r.Instances)
+		r.Unlock()
+	}
+	return total
+}
+
+func (f *Fleet) MonthlyCost() float64 {
+	var total float64
+	for _, r := range f.Regions {
+		r.Lock()
+		total += r.MonthlyCost()
+		r.Unlock()
+	}
+	return total
+}
+
+func (f *Fleet) AlarmCount() int {
+	var total int
+	for _, r := range f.Regions {
+		r.Lock()
+		total += len(r.AlarmsInState("ALARM"))
+		r.Unlock()
+	}
+	return total
+}
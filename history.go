@@ -0,0 +1,187 @@
+package window
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type (
+	// History bounds the Metrics series kept for each resource
+	History struct {
+		// a point within Resolution of the last replaces it
+		Resolution time.Duration
+		// points older than Retention are dropped
+		Retention time.Duration
+	}
+)
+
+var DefaultHistory = History{
+	Resolution: time.Minute,
+	Retention:  24 * time.Hour,
+}
+
+// Capacity is the most points a series holds
+func (h History) Capacity() int {
+	if h.Resolution <= 0 {
+		return 1
+	}
+	return int(h.Retention/h.Resolution) + 1
+}
+
+// add appends p to the series, dropping the oldest points beyond
+// the history's retention and capacity
+func (s *Series) add(p *Point, h History) {
+
+	if last := s.Last(); last != nil && p.Time.Sub(last.Time) < h.Resolution {
+		if !p.Time.Before(last.Time) {
+			s.Points[len(s.Points)-1] = p
+		}
+		return
+	}
+	s.Points = append(s.Points, p)
+
+	var n int
+	cutoff := p.Time.Add(-h.Retention)
+	for n < len(s.Points) && s.Points[n].Time.Before(cutoff) {
+		n++
+	}
+	if max := h.Capacity(); len(s.Points)-n > max {
+		n = len(s.Points) - max
+	}
+	if n > 0 {
+		s.Points = append([]*Point(nil), s.Points[n:]...)
+	}
+
+}
+
+// Range returns the points of the series between from and to, inclusive.
+// A zero from or to is unbounded.
+func (s *Series) Range(from, to time.Time) *Series {
+	r := &Series{Unit: s.Unit}
+	for _, p := range s.Points {
+		if (!from.IsZero() && p.Time.Before(from)) || (!to.IsZero() && p.Time.After(to)) {
+			continue
+		}
+		r.Points = append(r.Points, p)
+	}
+	return r
+}
+
+// Sparkline returns the series as svg polyline points scaled to
+// width x height, oldest on the left and the minimum at the bottom
+func (s *Series) Sparkline(width, height int) string {
+
+	if s == nil || len(s.Points) < 2 {
+		return ""
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, p := range s.Points {
+		min, max = math.Min(min, p.Value), math.Max(max, p.Value)
+	}
+	start, span := s.Points[0].Time, s.Points[len(s.Points)-1].Time.Sub(s.Points[0].Time)
+
+	points := make([]string, len(s.Points))
+	for i, p := range s.Points {
+		x := float64(width) * float64(p.Time.Sub(start)) / float64(span)
+		y := float64(height)
+		if max > min {
+			y -= float64(height) * (p.Value - min) / (max - min)
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+
+	return strings.Join(points, " ")
+
+}
+
+// resourceStats returns the cloudwatch stats of a resource, for
+// clusters the aggregate of their nodes
+func resourceStats(v interface{}) interface{} {
+	switch r := v.(type) {
+	case *ELB:
+		return r.Stats
+	case *DBInstance:
+		return r.Stats
+	case *ElasticCacheCluster:
+		if len(r.Stats) > 0 {
+			return r.AggregateStats()
+		}
+	case *SQSQueue:
+		return r.Stats
+	case *SNSTopic:
+		return r.Stats
+	case *LambdaFunction:
+		return r.Stats
+	}
+	return nil
+}
+
+// recordHistory adds the stats and defined metrics fetched by this refresh
+// to the series of the previous refresh's resources of the same key.  The
+// previous series are copied, not modified, as they may still be read.
+func (region *Region) recordHistory(prev map[string]interface{}, now time.Time) {
+
+	for key, v := range region.Items {
+
+		field := reflect.Indirect(reflect.ValueOf(v)).FieldByName("Metrics")
+		if !field.IsValid() {
+			continue
+		}
+
+		history := map[string]*Series{}
+		for name, s := range resourceMetrics(prev[key]) {
+			history[name] = &Series{Unit: s.Unit, Points: append([]*Point(nil), s.Points...)}
+		}
+
+		add := func(name, unit string, p *Point) {
+			s, exists := history[name]
+			if !exists {
+				s = &Series{Unit: unit}
+				history[name] = s
+			}
+			s.add(p, region.History)
+		}
+
+		for name, s := range resourceMetrics(v) {
+			if p := s.Last(); p != nil {
+				add(name, s.Unit, p)
+			}
+		}
+
+		if stats := resourceStats(v); stats != nil && !reflect.ValueOf(stats).IsNil() {
+			for _, sample := range appendStatSamples(nil, "", nil, reflect.ValueOf(stats)) {
+				add(sample.Name, "", &Point{Time: now, Value: sample.Value})
+			}
+		}
+
+		field.Set(reflect.ValueOf(history))
+
+	}
+
+}
+
+// ItemMetrics returns the named series of an item's Metrics, or all of
+// them if no names are given, limited to points between from and to.
+// The caller must hold the region's lock.
+func (region *Region) ItemMetrics(key string, names []string, from, to time.Time) (map[string]*Series, bool) {
+
+	v, exists := region.Items[key]
+	if !exists {
+		return nil, false
+	}
+
+	metrics := map[string]*Series{}
+	for name, s := range resourceMetrics(v) {
+		if len(names) > 0 && !StringInSlice(names, name) {
+			continue
+		}
+		metrics[name] = s.Range(from, to)
+	}
+
+	return metrics, true
+
+}
@@ -0,0 +1,86 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesAdd(t *testing.T) {
+
+	h := History{Resolution: time.Minute, Retention: 5 * time.Minute}
+	start := time.Unix(1500000000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	s := &Series{}
+	s.add(&Point{Time: at(0), Value: 1}, h)
+	s.add(&Point{Time: at(30 * time.Second), Value: 2}, h) // replaces, within resolution
+	s.add(&Point{Time: at(2 * time.Minute), Value: 3}, h)
+	s.add(&Point{Time: at(time.Minute), Value: 9}, h) // older than the last, dropped
+
+	if len(s.Points) != 2 || s.Points[0].Value != 2 || s.Points[1].Value != 3 {
+		t.Fatalf("unexpected points %v", s.Points)
+	}
+
+	s.add(&Point{Time: at(6 * time.Minute), Value: 4}, h)
+	if len(s.Points) != 2 || s.Points[0].Value != 3 {
+		t.Errorf("Expected points older than retention dropped, got %v", s.Points)
+	}
+
+	for i := 0; i < 20; i++ {
+		s.add(&Point{Time: at(time.Duration(7+i) * time.Minute), Value: float64(i)}, h)
+	}
+	if len(s.Points) != h.Capacity() {
+		t.Errorf("Expected %d points, got %d", h.Capacity(), len(s.Points))
+	}
+
+	r := s.Range(at(23*time.Minute), at(25*time.Minute))
+	if len(r.Points) != 3 || r.Points[0].Value != 16 {
+		t.Errorf("unexpected range %v", r.Points)
+	}
+
+}
+
+func TestSeriesSparkline(t *testing.T) {
+
+	start := time.Unix(1500000000, 0)
+	s := &Series{Points: []*Point{
+		{Time: start, Value: 10},
+		{Time: start.Add(time.Minute), Value: 20},
+		{Time: start.Add(2 * time.Minute), Value: 15},
+	}}
+
+	if points, expected := s.Sparkline(100, 10), "0.0,10.0 50.0,0.0 100.0,5.0"; points != expected {
+		t.Errorf("Expected %q, got %q", expected, points)
+	}
+
+	if points := (&Series{Points: s.Points[:1]}).Sparkline(100, 10); points != "" {
+		t.Errorf("Expected no sparkline for a single point, got %q", points)
+	}
+
+}
+
+func TestRegionRefreshRecordsHistory(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+	region.History = History{Resolution: time.Nanosecond, Retention: time.Hour}
+
+	client := region.Clients.CloudWatch.(*fakeCloudWatch)
+	for _, requests := range []float64{600, 1200} {
+		client.Values = map[string]float64{"RequestCount:Sum": requests}
+		if err := region.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	key := ItemKey(testAccountId, region.ELBs[0].Id)
+	metrics, exists := region.ItemMetrics(key, []string{"requests_per_second"}, time.Time{}, time.Time{})
+	if !exists {
+		t.Fatalf("%s not found", key)
+	}
+	s := metrics["requests_per_second"]
+	if len(metrics) != 1 || s == nil || len(s.Points) != 2 || s.Points[0].Value != 1 || s.Points[1].Value != 2 {
+		t.Errorf("unexpected history %v", metrics)
+	}
+
+}
@@ -15,10 +15,11 @@ type (
 		// Any tags assigned to the Internet gateway.
 		Tags []*ec2.Tag
 
-		Name  string
-		Id    string
-		State string
-		VPCs  []*VPC
+		Name      string
+		Id        string
+		AccountId string
+		State     string
+		VPCs      []*VPC
 	}
 
 	InternetGatewayByNameAsc []*InternetGateway
@@ -37,9 +38,9 @@ func (igw *InternetGateway) String() string {
 	return ""
 }
 
-func LoadInternetGateways(input *ec2.DescribeInternetGatewaysInput) (map[string]*InternetGateway, error) {
+func LoadInternetGateways(client EC2API, input *ec2.DescribeInternetGatewaysInput) (map[string]*InternetGateway, error) {
 
-	resp, err := EC2Client.DescribeInternetGateways(input)
+	resp, err := client.DescribeInternetGateways(input)
 	if err != nil {
 		return nil, err
 	}
@@ -1,10 +1,9 @@
 package window
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -149,6 +148,7 @@ type (
 
 		Name             string
 		Id               string
+		AccountId        string
 		State            string
 		Region           *Region
 		VPC              *VPC
@@ -165,17 +165,18 @@ type (
 		// true if server cannot be ssh polled by usual means
 		Unreachable       bool
 		UnreachableReason string
-		SysInfo           *sysinfo.SystemInfoCollector
-		Stats             *sysinfo.SystemInfoSummary
-		sysInfo_me        sync.RWMutex
+		// set while the instance's host key is rejected
+		HostKeyAlert string
+		SysInfo      *sysinfo.SystemInfoCollector `json:"-"`
+		Stats        *sysinfo.SystemInfoSummary
+		Metrics      map[string]*Series
+		sysInfo_me   sync.RWMutex
 	}
 
 	InstanceByNameAsc         []*Instance
 	NetworkInterfaceByNameAsc []*ec2.InstanceNetworkInterface
 )
 
-const DialTimeout = 3 * time.Second
-
 func (a InstanceByNameAsc) Len() int      { return len(a) }
 func (a InstanceByNameAsc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a InstanceByNameAsc) Less(i, j int) bool {
@@ -274,7 +275,7 @@ func (inst *Instance) NetworkOutNormal() float64 {
 	return 0
 }
 
-func LoadInstances(input *ec2.DescribeInstancesInput) (map[string]*Instance, error) {
+func LoadInstances(client EC2API, input *ec2.DescribeInstancesInput) (map[string]*Instance, error) {
 
 	if input == nil {
 		input = &ec2.DescribeInstancesInput{MaxResults: aws.Int64(1000)}
@@ -284,7 +285,7 @@ func LoadInstances(input *ec2.DescribeInstancesInput) (map[string]*Instance, err
 
 	instances := map[string]*Instance{}
 
-	if err := EC2Client.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+	if err := client.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
 		for _, res := range page.Reservations {
 			for _, ec2inst := range res.Instances {
 				instance := &Instance{
@@ -401,113 +402,146 @@ func (inst *Instance) Inactive() bool {
 	return false
 }
 
-func (inst *Instance) Poll() []chan error {
-
-	var errs []chan error
-
-	if inst.SysInfo != nil {
-		errs = append(errs, inst.Region.Throttle.do(inst.Name+" POLL", func() error {
-			err := inst.SysInfo.Poll()
-			inst.sysInfo_me.Lock()
-			defer inst.sysInfo_me.Unlock()
-			inst.Stats = inst.SysInfo.GetSummary()
+// poll collects the instance's sysinfo over ssh, or if the poller
+// streams, starts it streaming and reads the latest.  Without a collector
+// it tries each suggested user on each host open to port 22 until one
+// works, starting with the user and host that last worked.
+func (inst *Instance) poll(p *InstancePoller, state *instancePollState) error {
+
+	state.hostKeyErr = nil
+
+	inst.sysInfo_me.RLock()
+	si := inst.SysInfo
+	inst.sysInfo_me.RUnlock()
+
+	if si != nil {
+		var err error
+		if p.Stream > 0 {
+			if state.stream == nil {
+				state.stream = make(chan error, 1)
+				go func(errc chan error) {
+					errc <- si.Stream(p.Stream)
+				}(state.stream)
+			}
+			select {
+			case err = <-state.stream:
+				state.stream = nil
+				if err == nil {
+					err = errors.New("stream ended")
+				}
+			default:
+			}
+		} else {
+			err = si.Poll()
+		}
+		inst.sysInfo_me.Lock()
+		defer inst.sysInfo_me.Unlock()
+		if err != nil {
+			// find a working user and host again next time
+			si.Close()
+			inst.SysInfo = nil
+			if state.hostKeyErr != nil {
+				return state.hostKeyErr
+			}
 			return err
-		}))
-		return errs
-	}
-
-	switch {
-	case inst.Unreachable:
-		return nil
-	case aws.StringValue(inst.InstanceState.Name) != ec2.InstanceStateNameRunning:
-		inst.UnreachableReason = "Instance not running"
-		return nil
-	case len(inst.KeyName) == 0:
-		inst.Unreachable = true
-		inst.UnreachableReason = "Key not specified"
+		}
+		if summary := si.GetSummary(); summary != nil {
+			inst.Stats = summary
+		}
 		return nil
 	}
 
+	keys := p.keys(inst.Region)
+
 	hosts := hostsForPort(inst, 22)
-	if len(hosts) == 0 {
-		inst.UnreachableReason = "No ports open"
-		return nil
+
+	// behind a bastion only the private address is reachable
+	var dial func(network, addr string) (net.Conn, error)
+	if p.Bastions != nil {
+		if bastion := p.Bastions.For(inst); bastion != nil {
+			dial = p.Bastions.Dial(bastion, keys, p.KnownHosts)
+			hosts = nil
+			if len(inst.PrivateIpAddress) > 0 {
+				hosts = []string{inst.PrivateIpAddress + ":22"}
+			}
+		}
 	}
 
-	data, err := ioutil.ReadFile(filepath.Join(inst.Region.sshKeyPath, inst.KeyName+".pem"))
-	if err != nil {
-		inst.UnreachableReason = "Key file not found"
-		return nil
+	if len(hosts) == 0 {
+		return unreachableError("No ports open")
 	}
 
-	signer, err := ssh.ParsePrivateKey(data)
+	signers, err := signers(keys, inst.KeyName)
 	if err != nil {
-		inst.UnreachableReason = "Key file not valid"
-		return nil
+		return err
 	}
 
-	// default to this
-	inst.Unreachable = true
-	inst.UnreachableReason = "No valid user found"
+	type candidate struct{ user, host string }
 
-	users := []string{"ubuntu", "centos", "ec2-user", "admin"}
+	var candidates []candidate
+	if StringInSlice(hosts, state.host) {
+		candidates = append(candidates, candidate{state.user, state.host})
+	}
+	for _, host := range hosts {
+		for _, user := range p.users(inst) {
+			if user != state.user || host != state.host {
+				candidates = append(candidates, candidate{user, host})
+			}
+		}
+	}
 
-	try := func(user, host string) error {
+	dead := map[string]bool{}
 
-		config := &ssh.ClientConfig{
-			User: user,
-			Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
-		}
+	for _, c := range candidates {
 
-		conn, err := net.DialTimeout("tcp", host, DialTimeout)
-		if err != nil {
-			return err
+		if dead[c.host] {
+			continue
 		}
-		defer conn.Close()
 
-		c, chans, reqs, err := ssh.NewClientConn(conn, host, config)
-		if err != nil {
-			return err
-		}
-		client := ssh.NewClient(c, chans, reqs)
-		defer client.Close()
+		si := sysinfo.NewSystemInfoCollector(c.host, &ssh.ClientConfig{
+			User:            c.user,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+			HostKeyCallback: inst.hostKeyCallback(p, state),
+		}, 2)
+		si.Dial = dial
+		si.Extra = p.SysInfo.For(inst)
 
-		sess, err := client.NewSession()
+		err := si.Poll()
 		if err != nil {
-			return err
+			si.Close()
 		}
-		defer sess.Close()
-
-		inst.sysInfo_me.Lock()
-		defer inst.sysInfo_me.Unlock()
-		if inst.SysInfo == nil {
-			inst.SysInfo = sysinfo.NewSystemInfoCollector(host, config, 2)
-			err := inst.SysInfo.Poll()
-			inst.Stats = inst.SysInfo.GetSummary()
-			inst.Unreachable = false
-			inst.UnreachableReason = ""
-			return err
+		if state.hostKeyErr != nil {
+			// the key is the host's, not the user's, so don't try others
+			return state.hostKeyErr
 		}
-
-		return nil
-
-	}
-
-	for _, user := range users {
-		for _, host := range hosts {
-			user, host := user, host
-			errs = append(errs, inst.Region.Throttle.do(inst.Name+" POLL", func() error {
-				if err := try(user, host); err != nil && !strings.Contains(err.Error(), "ssh: handshake failed:") {
-					if neterr, ok := err.(net.Error); !ok || (!neterr.Timeout() && !neterr.Temporary()) {
-						return err
-					}
-				}
-				return nil
-			}))
+		if be, ok := err.(*BastionError); ok {
+			// nor is the bastion's
+			if _, ok := be.Err.(*HostKeyError); ok {
+				state.hostKeyErr = be
+			}
+			return be
 		}
+		switch {
+		case err == nil:
+			state.user, state.host = c.user, c.host
+			inst.sysInfo_me.Lock()
+			defer inst.sysInfo_me.Unlock()
+			inst.SysInfo = si
+			inst.Stats = si.GetSummary()
+			return nil
+		case strings.Contains(err.Error(), "ssh: handshake failed:"):
+			// wrong user, try the next
+		default:
+			if _, ok := err.(net.Error); !ok {
+				return err
+			}
+			// no other user will get through either
+			dead[c.host] = true
+		}
+
 	}
 
-	return errs
+	return unreachableError("No valid user found")
 
 }
 
@@ -0,0 +1,187 @@
+package window
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+type (
+	instmetric struct {
+		namespace  string
+		name       *string
+		statistics []*string
+		unit       *string
+		processor  func(*sysinfo.SystemInfoSummary, *cloudwatch.Datapoint)
+	}
+)
+
+var (
+	// InstanceMetrics fill in the stats of instances that aren't polled over
+	// ssh.  The CWAgent metrics are only found if the agent is configured with
+	// "append_dimensions": {"InstanceId": "${aws:InstanceId}"} and, for disk,
+	// "aggregation_dimensions": [["InstanceId"]].
+	InstanceMetrics = []*instmetric{
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("CPUUtilization"),
+			statistics: []*string{aws.String("Average")},
+			unit:       aws.String("Percent"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Average != nil {
+					stats.CPU.Id = "cpu"
+					stats.CPU.PercentInUse = *point.Average / 100
+					// no per cpu breakdown, show the aggregate as one
+					stats.CPU.CPUs = []sysinfo.CPUSummary{stats.CPU.CPUSummary}
+				}
+			},
+		},
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("NetworkIn"),
+			statistics: []*string{aws.String("Sum")},
+			unit:       aws.String("Bytes"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Sum != nil {
+					stats.Network.BytesIn = uint64(*point.Sum)
+					stats.Network.BytesPerSecondIn = uint64(*point.Sum / PeriodInMinutes / 60)
+				}
+			},
+		},
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("NetworkOut"),
+			statistics: []*string{aws.String("Sum")},
+			unit:       aws.String("Bytes"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Sum != nil {
+					stats.Network.BytesOut = uint64(*point.Sum)
+					stats.Network.BytesPerSecondOut = uint64(*point.Sum / PeriodInMinutes / 60)
+				}
+			},
+		},
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("DiskReadOps"),
+			statistics: []*string{aws.String("Sum")},
+			unit:       aws.String("Count"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Sum != nil {
+					stats.DiskIO.ReadsPerSecond = *point.Sum / PeriodInMinutes / 60
+				}
+			},
+		},
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("DiskWriteOps"),
+			statistics: []*string{aws.String("Sum")},
+			unit:       aws.String("Count"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Sum != nil {
+					stats.DiskIO.WritesPerSecond = *point.Sum / PeriodInMinutes / 60
+				}
+			},
+		},
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("StatusCheckFailed"),
+			statistics: []*string{aws.String("Maximum")},
+			unit:       aws.String("Count"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Maximum != nil {
+					stats.StatusCheckFailed = *point.Maximum > 0
+				}
+			},
+		},
+		{
+			namespace:  "AWS/EC2",
+			name:       aws.String("CPUCreditBalance"),
+			statistics: []*string{aws.String("Average")},
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Average != nil {
+					stats.CPUCreditBalance = *point.Average
+				}
+			},
+		},
+		{
+			namespace:  "CWAgent",
+			name:       aws.String("mem_used_percent"),
+			statistics: []*string{aws.String("Average")},
+			unit:       aws.String("Percent"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Average != nil {
+					stats.Memory.PercentUser = *point.Average / 100
+				}
+			},
+		},
+		{
+			namespace:  "CWAgent",
+			name:       aws.String("disk_used_percent"),
+			statistics: []*string{aws.String("Maximum")},
+			unit:       aws.String("Percent"),
+			processor: func(stats *sysinfo.SystemInfoSummary, point *cloudwatch.Datapoint) {
+				if point.Maximum != nil {
+					stats.Disk.PercentInUse = *point.Maximum / 100
+				}
+			},
+		},
+	}
+)
+
+// Query returns the metric query for inst, which records its result in
+// stats.  stats become the instance's Stats once any metric is fetched,
+// unless it has started being polled over ssh in the meantime.
+func (m *instmetric) Query(inst *Instance, stats *sysinfo.SystemInfoSummary) *MetricQuery {
+	return &MetricQuery{
+		Namespace: m.namespace,
+		Name:      m.name,
+		Dimensions: []*cloudwatch.Dimension{{
+			Name:  aws.String("InstanceId"),
+			Value: aws.String(inst.InstanceId),
+		}},
+		Statistics: m.statistics,
+		Unit:       m.unit,
+		processor: func(point *cloudwatch.Datapoint) {
+			inst.sysInfo_me.Lock()
+			defer inst.sysInfo_me.Unlock()
+			if inst.SysInfo != nil {
+				return
+			}
+			if point.Timestamp != nil && point.Timestamp.After(stats.Timestamp) {
+				stats.Timestamp = *point.Timestamp
+			}
+			m.processor(stats, point)
+			inst.Stats = stats
+		},
+	}
+}
+
+// PollMetrics queues the cloudwatch metrics of a running instance that
+// isn't polled over ssh.  Its previous stats are dropped so an instance
+// without metrics shows none.
+func (inst *Instance) PollMetrics(metrics *MetricFetcher) {
+
+	if aws.StringValue(inst.InstanceState.Name) != ec2.InstanceStateNameRunning {
+		return
+	}
+
+	inst.sysInfo_me.Lock()
+	defer inst.sysInfo_me.Unlock()
+
+	if inst.SysInfo != nil {
+		return
+	}
+	inst.Stats = nil
+
+	stats := &sysinfo.SystemInfoSummary{
+		Source:   sysinfo.SourceCloudWatch,
+		Duration: PeriodInMinutes * time.Minute,
+	}
+	for _, m := range InstanceMetrics {
+		metrics.Add(m.Query(inst, stats))
+	}
+
+}
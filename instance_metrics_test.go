@@ -0,0 +1,51 @@
+package window
+
+import (
+	"testing"
+
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+func TestRegionRefreshInstanceMetrics(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	region.Clients.CloudWatch.(*fakeCloudWatch).Values = map[string]float64{
+		"CPUUtilization:Average":    50,
+		"NetworkIn:Sum":             6000,
+		"StatusCheckFailed:Maximum": 1,
+		"mem_used_percent:Average":  25,
+	}
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inst := range region.Instances {
+		switch inst.InstanceId {
+		case "i-1":
+			if inst.Stats == nil || inst.Stats.Source != sysinfo.SourceCloudWatch {
+				t.Fatalf("Expected cloudwatch stats, got %v", inst.Stats)
+			}
+			if inst.CPU() != 50 || len(inst.CPUs()) != 1 {
+				t.Errorf("Expected 50%% cpu, got %d %v", inst.CPU(), inst.CPUs())
+			}
+			if inst.NetworkIn() != 10 {
+				t.Errorf("Expected 10 bytes per second in, got %d", inst.NetworkIn())
+			}
+			if inst.MemoryUser() != 25 {
+				t.Errorf("Expected 25%% memory, got %d", inst.MemoryUser())
+			}
+			if !inst.Stats.StatusCheckFailed {
+				t.Error("Expected status check failed")
+			}
+		case "i-2":
+			// not running
+			if inst.Stats != nil {
+				t.Errorf("Expected no stats for stopped instance, got %v", inst.Stats)
+			}
+		}
+	}
+
+}
@@ -0,0 +1,249 @@
+package window
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type (
+	// InstancePoller polls the sysinfo of a region's instances over ssh.
+	// Instances that can't be polled are retried with exponential backoff
+	// rather than given up on.
+	InstancePoller struct {
+		// unreachable instances are retried after MinBackoff, doubling
+		// with each failure up to MaxBackoff
+		MinBackoff time.Duration
+		MaxBackoff time.Duration
+
+		// if set, each instance streams a sample every Stream over a
+		// single long lived session rather than being polled.  Streaming
+		// sessions aren't counted against the session cap.
+		Stream time.Duration
+
+		// pins instance host keys, any key is accepted if nil
+		KnownHosts *KnownHosts
+		// verify new host keys against the instance's console output
+		VerifyConsole bool
+
+		// jump hosts to reach instances through, if any
+		Bastions *Bastions
+
+		// ssh keys to offer, the region's key files if none
+		Keys []KeyProvider
+		// login users to try, from the first provider to suggest any
+		Users []UserProvider
+
+		// extra sysinfo metrics to collect, if any
+		SysInfo *SysInfoConfig
+
+		// caps concurrent ssh sessions, separately from the api Throttle
+		sessions chan struct{}
+
+		// by instance id
+		states map[string]*instancePollState
+		me     sync.Mutex
+
+		// replaced in tests
+		poll func(*Instance, *instancePollState) error
+	}
+
+	instancePollState struct {
+		// the user and host that last worked
+		user, host string
+
+		polling  bool
+		failures int
+		retry    time.Time
+
+		// receives the error that ended the instance's Stream
+		stream chan error
+
+		// why the host key was last rejected
+		hostKeyErr error
+	}
+
+	// unreachableError is a reason an instance can't be polled
+	// that isn't worth reporting
+	unreachableError string
+)
+
+const (
+	DefaultSSHSessions = 10
+	DefaultMinBackoff  = time.Minute
+	DefaultMaxBackoff  = time.Hour
+)
+
+func (e unreachableError) Error() string { return string(e) }
+
+func NewInstancePoller(sessions int) *InstancePoller {
+	p := &InstancePoller{
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		sessions:   make(chan struct{}, sessions),
+		states:     map[string]*instancePollState{},
+		Users:      []UserProvider{TagUser(DefaultSSHUserTag), DefaultAMIUsers},
+	}
+	p.poll = func(inst *Instance, state *instancePollState) error {
+		return inst.poll(p, state)
+	}
+	return p
+}
+
+// backoff returns how long to wait before retrying an instance
+// that has failed this many times in a row
+func (p *InstancePoller) backoff(failures int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < failures && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Poll polls an instance unless it isn't running, is already being
+// polled or is backing off.  Reasons it can't be polled are recorded
+// in its UnreachableReason, only unexpected errors are returned.
+func (p *InstancePoller) Poll(inst *Instance) error {
+
+	if aws.StringValue(inst.InstanceState.Name) != ec2.InstanceStateNameRunning {
+		inst.sysInfo_me.Lock()
+		inst.UnreachableReason = "Instance not running"
+		if inst.SysInfo != nil {
+			inst.SysInfo.Close()
+			inst.SysInfo = nil
+		}
+		inst.sysInfo_me.Unlock()
+		// the closed collector's stream has ended
+		p.me.Lock()
+		if state, exists := p.states[inst.InstanceId]; exists && !state.polling {
+			state.stream = nil
+		}
+		p.me.Unlock()
+		return nil
+	}
+
+	p.me.Lock()
+	state, exists := p.states[inst.InstanceId]
+	if !exists {
+		state = &instancePollState{}
+		p.states[inst.InstanceId] = state
+	}
+	if state.polling || time.Now().Before(state.retry) {
+		p.me.Unlock()
+		return nil
+	}
+	state.polling = true
+	p.me.Unlock()
+
+	p.sessions <- struct{}{}
+	err := p.poll(inst, state)
+	<-p.sessions
+
+	p.me.Lock()
+	state.polling = false
+	if err != nil {
+		state.failures++
+		state.retry = time.Now().Add(p.backoff(state.failures))
+	} else {
+		state.failures = 0
+		state.retry = time.Time{}
+	}
+	p.me.Unlock()
+
+	inst.sysInfo_me.Lock()
+	inst.Unreachable = err != nil
+	inst.UnreachableReason = ""
+	if err != nil {
+		inst.UnreachableReason = err.Error()
+	}
+	if isHostKeyError(err) {
+		inst.HostKeyAlert = err.Error()
+	} else if err == nil {
+		inst.HostKeyAlert = ""
+	}
+	inst.sysInfo_me.Unlock()
+
+	if _, ok := err.(unreachableError); ok || err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%s POLL error: %v", inst.Name, err)
+
+}
+
+// prune forgets the state of instances that have left the region,
+// other than those still being polled
+func (p *InstancePoller) prune(ids map[string]bool) {
+	p.me.Lock()
+	defer p.me.Unlock()
+	for id, state := range p.states {
+		if !ids[id] && !state.polling {
+			delete(p.states, id)
+		}
+	}
+}
+
+// Run polls the region's instances forever, spreading each round of
+// polls evenly across interval.  polled is called after each round.
+func (p *InstancePoller) Run(region *Region, interval time.Duration, polled func()) {
+	for {
+		start := time.Now()
+		p.round(region, interval)
+		polled()
+		if d := interval - time.Since(start); d > 0 {
+			time.Sleep(d)
+		}
+	}
+}
+
+// round polls each of the region's instances once, starting a poll every
+// interval/instances.  Instances are looked up by key as they're polled
+// so polls follow the region across refreshes.
+func (p *InstancePoller) round(region *Region, interval time.Duration) {
+
+	region.Lock()
+	keys := make([]string, 0, len(region.Instances))
+	ids := make(map[string]bool, len(region.Instances))
+	for _, inst := range region.Instances {
+		keys = append(keys, ItemKey(inst.AccountId, inst.Id))
+		ids[inst.InstanceId] = true
+	}
+	region.Unlock()
+
+	p.prune(ids)
+
+	if len(keys) == 0 {
+		return
+	}
+
+	spacing := interval / time.Duration(len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		if i > 0 {
+			time.Sleep(spacing)
+		}
+		region.Lock()
+		inst, exists := region.Items[key].(*Instance)
+		region.Unlock()
+		if !exists {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Poll(inst); err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+}
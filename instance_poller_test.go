@@ -0,0 +1,205 @@
+package window
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+func runningInstance(id string) *Instance {
+	return &Instance{
+		Id:            "inst:" + id,
+		InstanceId:    id,
+		Name:          id,
+		AccountId:     testAccountId,
+		InstanceState: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+	}
+}
+
+func TestInstancePollerBackoff(t *testing.T) {
+
+	p := NewInstancePoller(1)
+	p.MinBackoff, p.MaxBackoff = time.Minute, 5*time.Minute
+
+	for failures, expected := range []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if failures == 0 {
+			continue
+		}
+		if d := p.backoff(failures); d != expected {
+			t.Errorf("%d failures: expected %s, got %s", failures, expected, d)
+		}
+	}
+
+	var polls int
+	fail := true
+	p.poll = func(*Instance, *instancePollState) error {
+		polls++
+		if fail {
+			return unreachableError("No ports open")
+		}
+		return nil
+	}
+
+	inst := runningInstance("i-1")
+	if err := p.Poll(inst); err != nil {
+		t.Fatal(err)
+	}
+	if !inst.Unreachable || inst.UnreachableReason != "No ports open" {
+		t.Errorf("Expected unreachable, got %v %q", inst.Unreachable, inst.UnreachableReason)
+	}
+
+	// backing off
+	p.Poll(inst)
+	if polls != 1 {
+		t.Errorf("Expected 1 poll while backing off, got %d", polls)
+	}
+
+	p.states["i-1"].retry = time.Time{}
+	fail = false
+	p.Poll(inst)
+	if polls != 2 || inst.Unreachable || p.states["i-1"].failures != 0 {
+		t.Errorf("Expected a reachable instance after retrying, got %d polls %v", polls, inst.Unreachable)
+	}
+
+	// unexpected errors are reported
+	p.poll = func(*Instance, *instancePollState) error { return errors.New("bad stat") }
+	if err := p.Poll(inst); err == nil || !strings.Contains(err.Error(), "bad stat") {
+		t.Errorf("Expected bad stat error, got %v", err)
+	}
+
+	// rejected host keys are raised on the instance until it polls again
+	p.states["i-1"].retry = time.Time{}
+	p.poll = func(*Instance, *instancePollState) error {
+		return &HostKeyError{InstanceId: "i-1", Fingerprint: "SHA256:x", Reason: "changed"}
+	}
+	p.Poll(inst)
+	if len(inst.HostKeyAlert) == 0 {
+		t.Error("Expected a host key alert")
+	}
+	p.states["i-1"].retry = time.Time{}
+	p.poll = func(*Instance, *instancePollState) error { return nil }
+	p.Poll(inst)
+	if len(inst.HostKeyAlert) > 0 {
+		t.Errorf("Expected the host key alert cleared, got %q", inst.HostKeyAlert)
+	}
+
+	// as are the bastion's
+	p.states["i-1"].retry = time.Time{}
+	p.poll = func(*Instance, *instancePollState) error {
+		return &BastionError{Host: "bastion:22", Err: &HostKeyError{InstanceId: "bastion:bastion:22", Fingerprint: "SHA256:x", Reason: "changed"}}
+	}
+	if err := p.Poll(inst); err == nil {
+		t.Error("Expected the bastion error reported")
+	}
+	if !strings.HasPrefix(inst.HostKeyAlert, "bastion bastion:22: ") {
+		t.Errorf("Expected a bastion host key alert, got %q", inst.HostKeyAlert)
+	}
+
+	stopped := runningInstance("i-2")
+	stopped.InstanceState.Name = aws.String(ec2.InstanceStateNameStopped)
+	stopped.SysInfo = sysinfo.NewSystemInfoCollector("", nil, 2)
+	if err := p.Poll(stopped); err != nil || stopped.UnreachableReason != "Instance not running" {
+		t.Errorf("Expected stopped instance skipped, got %v %q", err, stopped.UnreachableReason)
+	}
+	if stopped.SysInfo != nil {
+		t.Error("Expected the collector of a stopped instance closed")
+	}
+
+}
+
+func TestInstancePollerSessions(t *testing.T) {
+
+	const sessions = 3
+
+	p := NewInstancePoller(sessions)
+
+	var running, max int64
+	p.poll = func(*Instance, *instancePollState) error {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			m := atomic.LoadInt64(&max)
+			if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&running, -1)
+		return nil
+	}
+
+	region := newRegion("us-test-1")
+	region.Poller = p
+	for _, id := range []string{"i-1", "i-2", "i-3", "i-4", "i-5", "i-6", "i-7", "i-8"} {
+		region.Instances = append(region.Instances, runningInstance(id))
+	}
+
+	for _, errs := range region.RefreshInstances() {
+		for _, errc := range errs {
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if max != sessions {
+		t.Errorf("Expected at most %d concurrent polls, got %d", sessions, max)
+	}
+
+}
+
+func TestInstancePollerRound(t *testing.T) {
+
+	p := NewInstancePoller(DefaultSSHSessions)
+
+	var (
+		me     sync.Mutex
+		starts = map[string]time.Time{}
+	)
+	p.poll = func(inst *Instance, _ *instancePollState) error {
+		me.Lock()
+		defer me.Unlock()
+		starts[inst.InstanceId] = time.Now()
+		return nil
+	}
+
+	region := newRegion("us-test-1")
+	region.Poller = p
+	for _, id := range []string{"i-1", "i-2", "i-3"} {
+		inst := runningInstance(id)
+		region.Instances = append(region.Instances, inst)
+		region.Items[ItemKey(inst.AccountId, inst.Id)] = inst
+	}
+
+	// states of instances no longer in the region are forgotten
+	p.states["i-gone"] = &instancePollState{failures: 3}
+	p.states["i-busy"] = &instancePollState{polling: true}
+
+	const interval = 60 * time.Millisecond
+	p.round(region, interval)
+
+	if _, exists := p.states["i-gone"]; exists {
+		t.Error("Expected the state of an instance no longer in the region pruned")
+	}
+	if _, exists := p.states["i-busy"]; !exists {
+		t.Error("Expected the state of an instance still being polled kept")
+	}
+	if len(p.states) != 4 {
+		t.Errorf("Expected 4 states, got %d", len(p.states))
+	}
+
+	if len(starts) != 3 {
+		t.Fatalf("Expected 3 polls, got %v", starts)
+	}
+	// polls are started interval/3 apart
+	if d := starts["i-3"].Sub(starts["i-1"]); d < 2*interval/3 || d > interval {
+		t.Errorf("Expected polls spread across %s, first to last took %s", interval, d)
+	}
+
+}
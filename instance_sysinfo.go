@@ -0,0 +1,190 @@
+package window
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+type (
+	// BlockDeviceIO is a device's io rates alongside the ebs volume
+	// attached as it
+	BlockDeviceIO struct {
+		sysinfo.DeviceIOSummary
+		// as attached, e.g. /dev/sdf
+		DeviceName string
+		VolumeId   string
+	}
+
+	// InterfaceIO is an interface's rates alongside the eni attached as it
+	InterfaceIO struct {
+		sysinfo.InterfaceSummary
+		NetworkInterfaceId string
+		Description        string
+	}
+)
+
+const (
+	// health derived from an instance's stats
+	HealthUnknown  = "unknown"
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFailed   = "failed"
+)
+
+// Processes returns the top processes if the procinfo metric is collected
+func (inst *Instance) Processes() *sysinfo.ProcInfoSummary {
+	if inst.Stats != nil {
+		procs, _ := inst.Stats.Extra["procinfo"].(*sysinfo.ProcInfoSummary)
+		return procs
+	}
+	return nil
+}
+
+// Containers returns each container's usage if the containers metric
+// is collected
+func (inst *Instance) Containers() sysinfo.ContainersSummary {
+	if inst.Stats != nil {
+		containers, _ := inst.Stats.Extra["containers"].(sysinfo.ContainersSummary)
+		return containers
+	}
+	return nil
+}
+
+// BlockDevices returns the io of each block device if the diskstats
+// metric is collected.  Devices are matched to the instance's block
+// device mappings by nvme serial on nitro instances, otherwise by name,
+// as /dev/sdf is attached as xvdf under xen.
+func (inst *Instance) BlockDevices() []*BlockDeviceIO {
+
+	if inst.Stats == nil {
+		return nil
+	}
+	summary, _ := inst.Stats.Extra["diskstats"].(sysinfo.DiskStatsSummary)
+
+	var devices []*BlockDeviceIO
+
+	for _, io := range summary {
+
+		dev := &BlockDeviceIO{DeviceIOSummary: io}
+
+		for _, bdm := range inst.BlockDeviceMappings {
+			if bdm.Ebs == nil {
+				continue
+			}
+			volumeId := aws.StringValue(bdm.Ebs.VolumeId)
+			name := aws.StringValue(bdm.DeviceName)
+			if (len(io.Serial) > 0 && strings.Replace(volumeId, "-", "", 1) == io.Serial) || kernelDeviceName(name) == io.Name {
+				dev.DeviceName = name
+				dev.VolumeId = volumeId
+				break
+			}
+		}
+
+		devices = append(devices, dev)
+
+	}
+
+	return devices
+
+}
+
+// Interfaces returns the rates of each network interface if the netdev
+// metric is collected, matched to the instance's enis by mac address
+func (inst *Instance) Interfaces() []*InterfaceIO {
+
+	if inst.Stats == nil {
+		return nil
+	}
+	summary, _ := inst.Stats.Extra["netdev"].(sysinfo.NetDevSummary)
+
+	var ifaces []*InterfaceIO
+
+	for _, is := range summary {
+		iface := &InterfaceIO{InterfaceSummary: is}
+		for _, ni := range inst.NetworkInterfaces {
+			if strings.EqualFold(aws.StringValue(ni.MacAddress), is.MAC) {
+				iface.NetworkInterfaceId = aws.StringValue(ni.NetworkInterfaceId)
+				iface.Description = aws.StringValue(ni.Description)
+				break
+			}
+		}
+		ifaces = append(ifaces, iface)
+	}
+
+	return ifaces
+
+}
+
+// Sockets returns the tcp socket counts if the tcpsockets metric is collected
+func (inst *Instance) Sockets() *sysinfo.TCPSockets {
+	if inst.Stats != nil {
+		sockets, _ := inst.Stats.Extra["tcpsockets"].(*sysinfo.TCPSockets)
+		return sockets
+	}
+	return nil
+}
+
+// Services returns the failed and watched systemd units if the systemd
+// metric is collected
+func (inst *Instance) Services() *sysinfo.SystemdUnits {
+	if inst.Stats != nil {
+		units, _ := inst.Stats.Extra["systemd"].(*sysinfo.SystemdUnits)
+		return units
+	}
+	return nil
+}
+
+// Health is failed if the instance's status check or a systemd unit has
+// failed, degraded if a watched unit is down, whatever the cpu and memory
+// look like.  It is unknown without stats, and while units are collected
+// from the instance but can't be read over ssh, as the last ones seen may
+// have changed.
+func (inst *Instance) Health() string {
+	if inst.Stats == nil {
+		return HealthUnknown
+	}
+	if inst.Stats.StatusCheckFailed {
+		return HealthFailed
+	}
+	if inst.collects("systemd") && (inst.Unreachable || inst.Stats.Source != sysinfo.SourceSSH) {
+		return HealthUnknown
+	}
+	health := HealthOK
+	if units := inst.Services(); units != nil {
+		for _, unit := range units.Units {
+			if unit.Failed() {
+				return HealthFailed
+			}
+			if unit.Down() {
+				health = HealthDegraded
+			}
+		}
+	}
+	return health
+}
+
+// collects reports whether the region's poller is configured
+// to collect the named sysinfo metric from the instance
+func (inst *Instance) collects(name string) bool {
+	if inst.Region == nil || inst.Region.Poller == nil {
+		return false
+	}
+	for _, mc := range inst.Region.Poller.SysInfo.For(inst) {
+		if mc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// kernelDeviceName returns the name xen gives a mapped device,
+// e.g. xvdf for /dev/sdf and xvda for a root device of /dev/sda1
+func kernelDeviceName(name string) string {
+	name = strings.TrimPrefix(name, "/dev/")
+	if strings.HasPrefix(name, "sd") {
+		name = "xvd" + name[len("sd"):]
+	}
+	return strings.TrimRight(name, "0123456789")
+}
@@ -0,0 +1,138 @@
+package window
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+func TestInstanceBlockDevices(t *testing.T) {
+
+	inst := &Instance{
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-0root")}},
+			{DeviceName: aws.String("/dev/sdf"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-0a1b2c3d4e5f60718")}},
+			{DeviceName: aws.String("/dev/sdg"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-0data")}},
+		},
+	}
+
+	if devices := inst.BlockDevices(); devices != nil {
+		t.Errorf("Expected no devices without stats, got %v", devices)
+	}
+
+	inst.Stats = &sysinfo.SystemInfoSummary{
+		Extra: map[string]interface{}{
+			"diskstats": sysinfo.DiskStatsSummary{
+				{Name: "xvda"},
+				{Name: "nvme1n1", Serial: "vol0a1b2c3d4e5f60718"},
+				{Name: "xvdh"},
+			},
+		},
+	}
+
+	devices := inst.BlockDevices()
+	if len(devices) != 3 {
+		t.Fatalf("Expected 3 devices, got %d", len(devices))
+	}
+	for i, expected := range []struct{ device, volume string }{
+		{"/dev/sda1", "vol-0root"},
+		{"/dev/sdf", "vol-0a1b2c3d4e5f60718"},
+		{"", ""},
+	} {
+		if devices[i].DeviceName != expected.device || devices[i].VolumeId != expected.volume {
+			t.Errorf("%s: Expected %s %s, got %s %s", devices[i].Name, expected.device, expected.volume, devices[i].DeviceName, devices[i].VolumeId)
+		}
+	}
+
+}
+
+func TestInstanceInterfaces(t *testing.T) {
+
+	inst := &Instance{
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{MacAddress: aws.String("0a:1b:2c:3d:4e:5f"), NetworkInterfaceId: aws.String("eni-1"), Description: aws.String("primary")},
+			{MacAddress: aws.String("0a:1b:2c:3d:4e:60"), NetworkInterfaceId: aws.String("eni-2")},
+		},
+		Stats: &sysinfo.SystemInfoSummary{
+			Extra: map[string]interface{}{
+				"netdev": sysinfo.NetDevSummary{
+					{Name: "eth0", MAC: "0A:1B:2C:3D:4E:5F"},
+					{Name: "docker0", MAC: "02:42:ac:11:00:01"},
+				},
+			},
+		},
+	}
+
+	ifaces := inst.Interfaces()
+	if len(ifaces) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %d", len(ifaces))
+	}
+	if ifaces[0].NetworkInterfaceId != "eni-1" || ifaces[0].Description != "primary" {
+		t.Errorf("Expected eth0 on eni-1, got %+v", ifaces[0])
+	}
+	if len(ifaces[1].NetworkInterfaceId) > 0 {
+		t.Errorf("Expected docker0 without an eni, got %+v", ifaces[1])
+	}
+
+}
+
+func TestInstanceHealth(t *testing.T) {
+
+	inst := &Instance{}
+	if health := inst.Health(); health != HealthUnknown {
+		t.Errorf("Expected %s without stats, got %s", HealthUnknown, health)
+	}
+
+	units := &sysinfo.SystemdUnits{
+		Units: []sysinfo.Unit{
+			{Name: "sshd.service", LoadState: "loaded", ActiveState: "active", SubState: "running", Watched: true},
+		},
+	}
+	inst.Stats = &sysinfo.SystemInfoSummary{
+		Source: sysinfo.SourceSSH,
+		Extra:  map[string]interface{}{"systemd": units},
+	}
+	if health := inst.Health(); health != HealthOK {
+		t.Errorf("Expected %s, got %s", HealthOK, health)
+	}
+
+	units.Units = append(units.Units, sysinfo.Unit{Name: "redis.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead", Watched: true})
+	if health := inst.Health(); health != HealthDegraded {
+		t.Errorf("Expected %s with a watched unit down, got %s", HealthDegraded, health)
+	}
+
+	units.Units = append(units.Units, sysinfo.Unit{Name: "nginx.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"})
+	if health := inst.Health(); health != HealthFailed {
+		t.Errorf("Expected %s with a failed unit, got %s", HealthFailed, health)
+	}
+
+	// the failed unit can't be seen once ssh breaks
+	inst.Region = &Region{Poller: NewInstancePoller(1)}
+	inst.Region.Poller.SysInfo = &SysInfoConfig{Default: []string{"systemd"}}
+	inst.Unreachable = true
+	if health := inst.Health(); health != HealthUnknown {
+		t.Errorf("Expected %s while unreachable, got %s", HealthUnknown, health)
+	}
+
+	// nor in cloudwatch stats
+	inst.Unreachable = false
+	inst.Stats = &sysinfo.SystemInfoSummary{Source: sysinfo.SourceCloudWatch}
+	if health := inst.Health(); health != HealthUnknown {
+		t.Errorf("Expected %s from cloudwatch stats, got %s", HealthUnknown, health)
+	}
+
+	// without systemd collected there are no units to miss
+	inst.Region.Poller.SysInfo = nil
+	inst.Unreachable = true
+	if health := inst.Health(); health != HealthOK {
+		t.Errorf("Expected %s from cloudwatch stats while unreachable without systemd, got %s", HealthOK, health)
+	}
+
+	inst.Stats = &sysinfo.SystemInfoSummary{Source: sysinfo.SourceCloudWatch, StatusCheckFailed: true}
+	if health := inst.Health(); health != HealthFailed {
+		t.Errorf("Expected %s with a failed status check, got %s", HealthFailed, health)
+	}
+
+}
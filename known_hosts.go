@@ -0,0 +1,238 @@
+package window
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/crypto/ssh"
+)
+
+type (
+	// KnownHosts pins the ssh host key of each instance the first time it
+	// is seen.  Keys are kept in a known_hosts style file keyed by instance
+	// id rather than address, as addresses change and are reused, e.g.
+	//
+	//	i-0123456789abcdef0 ecdsa-sha2-nistp256 AAAAE2VjZHNh...
+	//
+	// Remove an instance's line and restart to accept a new key.
+	KnownHosts struct {
+		path string
+		keys map[string]ssh.PublicKey
+		me   sync.Mutex
+	}
+
+	// HostKeyError is returned when an instance presents a host key other
+	// than the one pinned for it, or one its console output doesn't list
+	HostKeyError struct {
+		InstanceId  string
+		Fingerprint string
+		Reason      string
+	}
+)
+
+const (
+	consoleFingerprintsBegin = "-----BEGIN SSH HOST KEY FINGERPRINTS-----"
+	consoleFingerprintsEnd   = "-----END SSH HOST KEY FINGERPRINTS-----"
+)
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("%s host key %s %s", e.InstanceId, e.Fingerprint, e.Reason)
+}
+
+// isHostKeyError reports whether err is a rejected host key,
+// the instance's or its bastion's
+func isHostKeyError(err error) bool {
+	if be, ok := err.(*BastionError); ok {
+		err = be.Err
+	}
+	_, ok := err.(*HostKeyError)
+	return ok
+}
+
+// LoadKnownHosts reads the pinned keys at path, which need not exist yet
+func LoadKnownHosts(path string) (*KnownHosts, error) {
+
+	kh := &KnownHosts{
+		path: path,
+		keys: map[string]ssh.PublicKey{},
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return kh, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var hosts []string
+		var key ssh.PublicKey
+		_, hosts, key, _, data, err = ssh.ParseKnownHosts(data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, id := range hosts {
+			kh.keys[id] = key
+		}
+	}
+
+	return kh, nil
+
+}
+
+// Callback returns a host key callback for the instance.  Its pinned key
+// must match.  Without one, verify is called, if set, before the key
+// is pinned.
+func (kh *KnownHosts) Callback(instanceId string, verify func(ssh.PublicKey) error) ssh.HostKeyCallback {
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+
+		kh.me.Lock()
+		pinned, exists := kh.keys[instanceId]
+		kh.me.Unlock()
+
+		if exists {
+			if !bytes.Equal(pinned.Marshal(), key.Marshal()) {
+				return &HostKeyError{
+					InstanceId:  instanceId,
+					Fingerprint: ssh.FingerprintSHA256(key),
+					Reason:      "changed from pinned " + ssh.FingerprintSHA256(pinned),
+				}
+			}
+			return nil
+		}
+
+		if verify != nil {
+			if err := verify(key); err != nil {
+				return err
+			}
+		}
+
+		kh.me.Lock()
+		defer kh.me.Unlock()
+
+		f, err := os.OpenFile(kh.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := fmt.Fprintf(f, "%s %s", instanceId, ssh.MarshalAuthorizedKey(key)); err != nil {
+			return err
+		}
+
+		kh.keys[instanceId] = key
+
+		return nil
+
+	}
+}
+
+// ConsoleFingerprints returns the ssh host key fingerprints cloud-init
+// prints to an instance's console on boot
+func ConsoleFingerprints(output string) []string {
+
+	var (
+		fingerprints []string
+		inside       bool
+	)
+
+	s := bufio.NewScanner(strings.NewReader(output))
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case strings.Contains(line, consoleFingerprintsBegin):
+			inside = true
+		case strings.Contains(line, consoleFingerprintsEnd):
+			inside = false
+		case inside:
+			// e.g. "ec2: 256 SHA256:abc... root@ip-10-0-0-1 (ECDSA)"
+			// or the older "2048 1a:2b:...:3c /etc/ssh/ssh_host_rsa_key.pub (RSA)"
+			for _, field := range strings.Fields(line) {
+				if strings.HasPrefix(field, "SHA256:") || strings.HasPrefix(field, "MD5:") || strings.Count(field, ":") == 15 {
+					fingerprints = append(fingerprints, strings.TrimPrefix(field, "MD5:"))
+				}
+			}
+		}
+	}
+
+	return fingerprints
+
+}
+
+// verifyHostKey checks a new host key against the fingerprints
+// in the instance's console output
+func (inst *Instance) verifyHostKey(key ssh.PublicKey) error {
+
+	var output string
+	if err := <-inst.Region.Throttle.do(inst.Name+" CONSOLE OUTPUT", func() error {
+		resp, err := inst.Region.Clients.EC2.GetConsoleOutput(&ec2.GetConsoleOutputInput{
+			InstanceId: aws.String(inst.InstanceId),
+		})
+		if err != nil {
+			return err
+		}
+		data, err := base64.StdEncoding.DecodeString(aws.StringValue(resp.Output))
+		if err != nil {
+			return err
+		}
+		output = string(data)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fingerprints := ConsoleFingerprints(output)
+	if len(fingerprints) == 0 {
+		return errors.New("no host key fingerprints in console output")
+	}
+
+	sha256, md5 := ssh.FingerprintSHA256(key), ssh.FingerprintLegacyMD5(key)
+	for _, fingerprint := range fingerprints {
+		if fingerprint == sha256 || fingerprint == md5 {
+			return nil
+		}
+	}
+
+	return &HostKeyError{
+		InstanceId:  inst.InstanceId,
+		Fingerprint: sha256,
+		Reason:      "not in console output",
+	}
+
+}
+
+// hostKeyCallback checks host keys against the poller's KnownHosts,
+// recording the error of a rejected key in state.  Without KnownHosts
+// any key is accepted.
+func (inst *Instance) hostKeyCallback(p *InstancePoller, state *instancePollState) ssh.HostKeyCallback {
+
+	if p.KnownHosts == nil {
+		return ssh.InsecureIgnoreHostKey()
+	}
+
+	var verify func(ssh.PublicKey) error
+	if p.VerifyConsole {
+		verify = inst.verifyHostKey
+	}
+	check := p.KnownHosts.Callback(inst.InstanceId, verify)
+
+	return func(host string, remote net.Addr, key ssh.PublicKey) error {
+		state.hostKeyErr = check(host, remote, key)
+		return state.hostKeyErr
+	}
+
+}
@@ -0,0 +1,120 @@
+package window
+
+import (
+	"crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKnownHosts(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-known-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "known_hosts")
+
+	kh, err := LoadKnownHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, other := newHostKey(t), newHostKey(t)
+
+	// rejected by verification, so not pinned
+	reject := func(ssh.PublicKey) error { return &HostKeyError{Reason: "not in console output"} }
+	if err := kh.Callback("i-1", reject)("", nil, other); err == nil {
+		t.Error("Expected verification error")
+	}
+
+	if err := kh.Callback("i-1", nil)("", nil, key); err != nil {
+		t.Fatal(err)
+	}
+
+	kh, err = LoadKnownHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kh.Callback("i-1", reject)("", nil, key); err != nil {
+		t.Errorf("Expected pinned key accepted, got %v", err)
+	}
+	if err, ok := kh.Callback("i-1", nil)("", nil, other).(*HostKeyError); !ok || err.InstanceId != "i-1" {
+		t.Errorf("Expected changed key rejected, got %v", err)
+	}
+	if err := kh.Callback("i-2", nil)("", nil, other); err != nil {
+		t.Errorf("Expected key pinned per instance, got %v", err)
+	}
+
+}
+
+func TestConsoleFingerprints(t *testing.T) {
+
+	const output = `[   12.345678] cloud-init[1234]: Cloud-init v. 19.3 running 'modules:final'
+ec2: 
+ec2: #############################################################
+ec2: -----BEGIN SSH HOST KEY FINGERPRINTS-----
+ec2: 256 SHA256:2Nl0pDC5ZV3pw0GsBclJz7ZAXTMZX0oOFoSDfPJ2kyQ root@ip-10-0-0-1 (ECDSA)
+ec2: 2048 MD5:1a:2b:3c:4d:5e:6f:7a:8b:9c:0d:1e:2f:3a:4b:5c:6d root@ip-10-0-0-1 (RSA)
+ec2: -----END SSH HOST KEY FINGERPRINTS-----
+2048 00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd:ee:ff /etc/ssh/ssh_host_rsa_key.pub (RSA)
+`
+
+	expected := []string{
+		"SHA256:2Nl0pDC5ZV3pw0GsBclJz7ZAXTMZX0oOFoSDfPJ2kyQ",
+		"1a:2b:3c:4d:5e:6f:7a:8b:9c:0d:1e:2f:3a:4b:5c:6d",
+	}
+	if fingerprints := ConsoleFingerprints(output); !reflect.DeepEqual(fingerprints, expected) {
+		t.Errorf("Expected %v, got %v", expected, fingerprints)
+	}
+
+}
+
+func TestInstanceVerifyHostKey(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	key, other := newHostKey(t), newHostKey(t)
+	region.Clients.EC2.(*fakeEC2).ConsoleOutputs = map[string]string{
+		"i-1": consoleFingerprintsBegin + "\n256 " + ssh.FingerprintSHA256(key) + " root@web-1 (ED25519)\n" + consoleFingerprintsEnd + "\n",
+	}
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inst := range region.Instances {
+		switch inst.InstanceId {
+		case "i-1":
+			if err := inst.verifyHostKey(key); err != nil {
+				t.Errorf("Expected key verified, got %v", err)
+			}
+			if _, ok := inst.verifyHostKey(other).(*HostKeyError); !ok {
+				t.Error("Expected other key rejected")
+			}
+		case "i-2":
+			if err := inst.verifyHostKey(key); err == nil {
+				t.Error("Expected an error without console fingerprints")
+			}
+		}
+	}
+
+}
@@ -56,6 +56,7 @@ type (
 
 		Name             string
 		Id               string
+		AccountId        string
 		State            string
 		LastModifiedTime time.Time
 		Region           *Region
@@ -64,6 +65,7 @@ type (
 		Subnets          []*Subnet
 		CloudWatchAlarms []*CloudWatchAlarm
 		Stats            *LambdaFunctionStats
+		Metrics          map[string]*Series
 	}
 
 	LambdaFunctionsByNameAsc []*LambdaFunction
@@ -75,11 +77,11 @@ func (a LambdaFunctionsByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadLambdaFunctions(input *lambda.ListFunctionsInput) (map[string]*LambdaFunction, error) {
+func LoadLambdaFunctions(client LambdaAPI, input *lambda.ListFunctionsInput) (map[string]*LambdaFunction, error) {
 
 	funcs := map[string]*LambdaFunction{}
 
-	if err := LambdaClient.ListFunctionsPages(input, func(p *lambda.ListFunctionsOutput, _ bool) bool {
+	if err := client.ListFunctionsPages(input, func(p *lambda.ListFunctionsOutput, _ bool) bool {
 		for _, f := range p.Functions {
 			lf := &LambdaFunction{
 				CodeSha256:   aws.StringValue(f.CodeSha256),
@@ -111,19 +113,15 @@ func LoadLambdaFunctions(input *lambda.ListFunctionsInput) (map[string]*LambdaFu
 
 }
 
-func (lf *LambdaFunction) Poll() []chan error {
+func (lf *LambdaFunction) Poll(metrics *MetricFetcher) []chan error {
 
-	var errs []chan error
 	lf.Stats = &LambdaFunctionStats{}
 
 	for _, m := range LambdaFunctionMetrics {
-		m := m
-		errs = append(errs, lf.Region.Throttle.do(lf.Name+":"+*m.name+" Lambda METRICS POLL", func() error {
-			return m.RunFor(lf)
-		}))
+		metrics.Add(m.Query(lf))
 	}
 
-	return errs
+	return nil
 
 }
 
@@ -105,27 +105,18 @@ var (
 	}
 )
 
-func (m *lfmetric) RunFor(lf *LambdaFunction) error {
-
-	resp, err := CloudWatchClient.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
-		StartTime: aws.Time(time.Now().Add(-PeriodInMinutes * time.Minute)),
-		EndTime:   aws.Time(time.Now()),
-		Period:    aws.Int64(PeriodInMinutes * 60),
-		Namespace: aws.String("AWS/Lambda"),
-		Dimensions: []*cloudwatch.Dimension{
-			{
-				Name:  aws.String("FunctionName"),
-				Value: aws.String(lf.FunctionName),
-			},
-		},
-		MetricName: m.name,
+func (m *lfmetric) Query(lf *LambdaFunction) *MetricQuery {
+	return &MetricQuery{
+		Namespace: "AWS/Lambda",
+		Name:      m.name,
+		Dimensions: []*cloudwatch.Dimension{{
+			Name:  aws.String("FunctionName"),
+			Value: aws.String(lf.FunctionName),
+		}},
 		Statistics: m.statistics,
 		Unit:       m.unit, // fuck this in teh face
-	})
-	if err == nil && len(resp.Datapoints) > 0 {
-		m.processor(lf.Stats, resp.Datapoints[0])
+		processor: func(point *cloudwatch.Datapoint) {
+			m.processor(lf.Stats, point)
+		},
 	}
-
-	return err
-
 }
@@ -0,0 +1,119 @@
+package window
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+type (
+	// LoaderStatus is the outcome of a Refresh loader, carried
+	// across refreshes so failures can be shown alongside stale data
+	LoaderStatus struct {
+		Name        string
+		LastAttempt time.Time
+		LastSuccess time.Time
+		LastError   string
+		Duration    time.Duration
+	}
+
+	LoaderStatusByNameAsc []*LoaderStatus
+
+	// the results of the last refresh's loaders, reused
+	// in place of the results of any loader that fails
+	loaded struct {
+		vpcs                    map[string]*VPC
+		security_groups         map[string]*SecurityGroup
+		acls                    map[string]*ACL
+		route_tables            map[string]*RouteTable
+		subnets                 map[string]*Subnet
+		elbs                    map[string]*ELB
+		instances               map[string]*Instance
+		availability_zones      map[string]*AvailabilityZone
+		internet_gateways       map[string]*InternetGateway
+		customer_gateways       map[string]*CustomerGateway
+		vp_gateways             map[string]*VPGateway
+		vpn_connections         map[string]*VPNConnection
+		vpc_endpoints           map[string]*VPCEndpoint
+		vpc_peering_connections map[string]*VPCPeeringConnection
+		as_groups               map[string]*AutoScalingGroup
+		amis                    map[string]*AMI
+		db_instances            map[string]*DBInstance
+		ec_clusters             map[string]*ElasticCacheCluster
+		sqs_queues              map[string]*SQSQueue
+		sns_topics              map[string]*SNSTopic
+		sns_subscribers         map[string]*SNSSubscription
+		cloudwatch_alarms       map[string]*CloudWatchAlarm
+		lambda_functions        map[string]*LambdaFunction
+		enis                    map[string]*ENI
+		nat_gateways            map[string]*NATGateway
+	}
+)
+
+// types whose pointers are set when Refresh links a region together
+var linkTypes = map[reflect.Type]bool{}
+
+func init() {
+	for _, v := range []interface{}{
+		&Region{}, &Classic{}, &VPC{}, &SecurityGroup{}, &ACL{}, &RouteTable{},
+		&Subnet{}, &ELB{}, &Instance{}, &AvailabilityZone{}, &InternetGateway{},
+		&CustomerGateway{}, &VPGateway{}, &VPNConnection{}, &VPCEndpoint{},
+		&VPCPeeringConnection{}, &AutoScalingGroup{}, &AMI{}, &DBInstance{},
+		&ElasticCacheCluster{}, &SQSQueue{}, &SNSTopic{}, &SNSSubscription{},
+		&CloudWatchAlarm{}, &LambdaFunction{}, &ENI{}, &NATGateway{},
+	} {
+		linkTypes[reflect.TypeOf(v)] = true
+	}
+}
+
+func (a LoaderStatusByNameAsc) Len() int      { return len(a) }
+func (a LoaderStatusByNameAsc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a LoaderStatusByNameAsc) Less(i, j int) bool {
+	return string_less_than(a[i].Name, a[j].Name)
+}
+
+func (s *LoaderStatus) Failing() bool {
+	return len(s.LastError) > 0
+}
+
+// unlinked takes a map of resources from a previous refresh and returns
+// a map of shallow copies with all links to other resources cleared,
+// ready to be linked into a new region.  The previous region is untouched.
+func unlinked(m interface{}) interface{} {
+
+	src := reflect.ValueOf(m)
+	if src.IsNil() {
+		return m
+	}
+
+	dst := reflect.MakeMap(src.Type())
+
+	for _, key := range src.MapKeys() {
+		v := src.MapIndex(key)
+		if v.IsNil() {
+			continue
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(v.Elem())
+		for i, elem := 0, cp.Elem(); i < elem.NumField(); i++ {
+			field := elem.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			switch t := field.Type(); {
+			case linkTypes[t], t.Kind() == reflect.Slice && linkTypes[t.Elem()]:
+				field.Set(reflect.Zero(t))
+			}
+		}
+		switch r := cp.Interface().(type) {
+		case *VPC:
+			r.azs = map[*AvailabilityZone]*AvailabilityZone{}
+		case *Instance:
+			r.sysInfo_me = sync.RWMutex{}
+		}
+		dst.SetMapIndex(key, cp)
+	}
+
+	return dst.Interface()
+
+}
@@ -0,0 +1,240 @@
+package window
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+type (
+	// MetricDefinition declares a cloudwatch metric to collect for every
+	// resource of a kind.  Dimension values are text/templates executed
+	// with the resource, e.g.
+	//
+	//	{
+	//		"name": "elb_5xx",
+	//		"kind": "ELB",
+	//		"namespace": "AWS/ELB",
+	//		"metric": "HTTPCode_ELB_5XX",
+	//		"dimensions": {"LoadBalancerName": "{{.Name}}"},
+	//		"statistic": "Sum",
+	//		"per_second": true
+	//	}
+	//
+	// The results are kept in the resource's Metrics under name.
+	MetricDefinition struct {
+		Name       string            `json:"name"`
+		Kind       string            `json:"kind"`
+		Namespace  string            `json:"namespace"`
+		Metric     string            `json:"metric"`
+		Dimensions map[string]string `json:"dimensions"`
+		Statistic  string            `json:"statistic"`
+		Unit       string            `json:"unit,omitempty"`
+		// divide the statistic by the period, for Sum rates
+		PerSecond bool `json:"per_second,omitempty"`
+
+		dimensions map[string]*template.Template
+	}
+
+	// Series is the history of one of a resource's Metrics, oldest first
+	Series struct {
+		Unit   string
+		Points []*Point
+	}
+
+	Point struct {
+		Time  time.Time
+		Value float64
+	}
+)
+
+// LoadMetricDefinitions reads a json array of metric definitions
+func LoadMetricDefinitions(path string) ([]*MetricDefinition, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var defs []*MetricDefinition
+	if err := json.NewDecoder(f).Decode(&defs); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for _, def := range defs {
+		if err := def.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	return defs, nil
+
+}
+
+func (def *MetricDefinition) compile() error {
+
+	if len(def.Name) == 0 {
+		def.Name = def.Metric
+	}
+
+	t, exists := kindTypes[def.Kind]
+	if !exists {
+		return fmt.Errorf("%s: unknown kind %q", def.Name, def.Kind)
+	}
+	if f, exists := t.FieldByName("Metrics"); !exists || f.Type != reflect.TypeOf(map[string]*Series(nil)) {
+		return fmt.Errorf("%s: %s resources have no Metrics", def.Name, def.Kind)
+	}
+	if len(def.Namespace) == 0 || len(def.Metric) == 0 || len(def.Statistic) == 0 {
+		return fmt.Errorf("%s: namespace, metric and statistic are required", def.Name)
+	}
+
+	def.dimensions = map[string]*template.Template{}
+	for name, text := range def.Dimensions {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			return fmt.Errorf("%s: dimension %s: %v", def.Name, name, err)
+		}
+		def.dimensions[name] = tmpl
+	}
+
+	return nil
+
+}
+
+// query returns the metric query for a resource of the definition's kind,
+// which records its result in series
+func (def *MetricDefinition) query(v interface{}, series *Series) (*MetricQuery, error) {
+
+	if def.dimensions == nil {
+		if err := def.compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(def.dimensions))
+	for name := range def.dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dimensions []*cloudwatch.Dimension
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := def.dimensions[name].Execute(&buf, v); err != nil {
+			return nil, fmt.Errorf("%s: dimension %s: %v", def.Name, name, err)
+		}
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(name),
+			Value: aws.String(buf.String()),
+		})
+	}
+
+	q := &MetricQuery{
+		Namespace:  def.Namespace,
+		Name:       aws.String(def.Metric),
+		Dimensions: dimensions,
+		Statistics: []*string{aws.String(def.Statistic)},
+		processor: func(point *cloudwatch.Datapoint) {
+			value := datapointStat(point, def.Statistic)
+			if value == nil {
+				return
+			}
+			p := &Point{Time: time.Now(), Value: *value}
+			if point.Timestamp != nil {
+				p.Time = *point.Timestamp
+			}
+			if def.PerSecond {
+				p.Value /= PeriodInMinutes * 60
+			}
+			series.Points = []*Point{p}
+		},
+	}
+	if len(def.Unit) > 0 {
+		q.Unit = aws.String(def.Unit)
+	}
+
+	return q, nil
+
+}
+
+// PollMetricDefinitions queues the defined metrics of each resource.
+// Each resource's Metrics are replaced with an empty series per definition
+// before any are fetched, so the fetches only write to their own series.
+// The series are merged into the resource's history once fetched.
+func (region *Region) PollMetricDefinitions(metrics *MetricFetcher) error {
+
+	keys := make([]string, 0, len(region.Items))
+	for key := range region.Items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var failed int
+	var first error
+
+	for _, key := range keys {
+		v := region.Items[key]
+		kind := resourceKind(v)
+		field := reflect.Indirect(reflect.ValueOf(v)).FieldByName("Metrics")
+		if !field.IsValid() {
+			continue
+		}
+		series := map[string]*Series{}
+		for _, def := range region.MetricDefinitions {
+			if def.Kind == kind {
+				series[def.Name] = &Series{Unit: def.Unit}
+			}
+		}
+		field.Set(reflect.ValueOf(series))
+		for _, def := range region.MetricDefinitions {
+			if def.Kind != kind {
+				continue
+			}
+			q, err := def.query(v, series[def.Name])
+			if err != nil {
+				if failed++; first == nil {
+					first = fmt.Errorf("%s: %v", key, err)
+				}
+				continue
+			}
+			metrics.Add(q)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d defined metrics failed, first: %v", failed, first)
+	}
+
+	return nil
+
+}
+
+// resourceMetrics returns the Metrics of a resource, if it has them
+func resourceMetrics(v interface{}) map[string]*Series {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	if field := val.FieldByName("Metrics"); field.IsValid() {
+		m, _ := field.Interface().(map[string]*Series)
+		return m
+	}
+	return nil
+}
+
+// Last returns the latest point of the series, or nil
+func (s *Series) Last() *Point {
+	if s == nil || len(s.Points) == 0 {
+		return nil
+	}
+	return s.Points[len(s.Points)-1]
+}
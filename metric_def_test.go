@@ -0,0 +1,83 @@
+package window
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMetricDefinitions(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		json string
+		err  string
+	}{
+		{`[{"kind": "ELB", "namespace": "AWS/ELB", "metric": "HTTPCode_ELB_5XX", "statistic": "Sum", "dimensions": {"LoadBalancerName": "{{.Name}}"}}]`, ""},
+		{`[{"kind": "Bucket", "namespace": "AWS/S3", "metric": "BucketSizeBytes", "statistic": "Average"}]`, `unknown kind "Bucket"`},
+		{`[{"kind": "VPC", "namespace": "AWS/EC2", "metric": "NetworkIn", "statistic": "Sum"}]`, "VPC resources have no Metrics"},
+		{`[{"kind": "ELB", "namespace": "AWS/ELB", "metric": "Latency"}]`, "statistic are required"},
+		{`[{"kind": "ELB", "namespace": "AWS/ELB", "metric": "Latency", "statistic": "Average", "dimensions": {"LoadBalancerName": "{{.Name"}}]`, "dimension LoadBalancerName"},
+	} {
+		path := filepath.Join(dir, "metrics.json")
+		if err := ioutil.WriteFile(path, []byte(test.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defs, err := LoadMetricDefinitions(path)
+		switch {
+		case len(test.err) == 0 && err != nil:
+			t.Errorf("%s: %v", test.json, err)
+		case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: Expected error %q, got %v", test.json, test.err, err)
+		case err == nil && defs[0].Name != "HTTPCode_ELB_5XX":
+			t.Errorf("Expected name to default to the metric, got %q", defs[0].Name)
+		}
+	}
+
+}
+
+func TestRegionRefreshDefinedMetrics(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	def := &MetricDefinition{
+		Name:       "elb_5xx",
+		Kind:       "ELB",
+		Namespace:  "AWS/ELB",
+		Metric:     "HTTPCode_ELB_5XX",
+		Dimensions: map[string]string{"LoadBalancerName": "{{.Name}}"},
+		Statistic:  "Sum",
+		PerSecond:  true,
+	}
+	if err := def.compile(); err != nil {
+		t.Fatal(err)
+	}
+	region.MetricDefinitions = []*MetricDefinition{def}
+
+	region.Clients.CloudWatch.(*fakeCloudWatch).Values = map[string]float64{
+		"HTTPCode_ELB_5XX:Sum": 1200,
+	}
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	elb := region.ELBs[0]
+	if p := elb.Metrics["elb_5xx"].Last(); p == nil || p.Value != 2 {
+		t.Errorf("Expected 2 per second, got %v", p)
+	}
+
+	// resources of other kinds are left alone
+	if _, exists := region.DBInstances[0].Metrics["elb_5xx"]; exists {
+		t.Errorf("Expected no elb_5xx db metric, got %v", region.DBInstances[0].Metrics)
+	}
+
+}
@@ -0,0 +1,179 @@
+package window
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+type (
+	// MetricQuery is one cloudwatch metric of one resource.  processor is
+	// called with a datapoint holding the latest value of each statistic,
+	// if any of the statistics have a value.
+	MetricQuery struct {
+		Namespace  string
+		Name       *string
+		Dimensions []*cloudwatch.Dimension
+		Statistics []*string
+		Unit       *string
+		processor  func(*cloudwatch.Datapoint)
+	}
+
+	// MetricFetcher collects the metric queries of every Poll in a refresh
+	// and fetches them in as few GetMetricData requests as possible.
+	MetricFetcher struct {
+		queries []*MetricQuery
+		me      sync.Mutex
+	}
+)
+
+// the most queries GetMetricData accepts in one request
+const MaxMetricDataQueries = 500
+
+func (f *MetricFetcher) Add(q *MetricQuery) {
+	f.me.Lock()
+	defer f.me.Unlock()
+	f.queries = append(f.queries, q)
+}
+
+// Fetch issues the collected queries in batches of up to MaxMetricDataQueries
+// statistics through the throttle, and passes the results to the queries'
+// processors.  The queries are cleared.
+func (f *MetricFetcher) Fetch(client CloudWatchAPI, t *throttle) []chan error {
+
+	f.me.Lock()
+	queries := f.queries
+	f.queries = nil
+	f.me.Unlock()
+
+	var (
+		errs  []chan error
+		batch []*MetricQuery
+		size  int
+	)
+
+	now := time.Now()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		batch := batch
+		errs = append(errs, t.do(fmt.Sprintf("GetMetricData (%d metrics)", len(batch)), func() error {
+			return fetchMetricData(client, batch, now)
+		}))
+	}
+
+	for _, q := range queries {
+		// a query's statistics are kept in a single request
+		if size+len(q.Statistics) > MaxMetricDataQueries {
+			flush()
+			batch, size = nil, 0
+		}
+		batch = append(batch, q)
+		size += len(q.Statistics)
+	}
+	flush()
+
+	return errs
+
+}
+
+func fetchMetricData(client CloudWatchAPI, queries []*MetricQuery, now time.Time) error {
+
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(now.Add(-PeriodInMinutes * time.Minute)),
+		EndTime:   aws.Time(now),
+		ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+	}
+
+	points := make([]*cloudwatch.Datapoint, len(queries))
+	type result struct {
+		query int
+		stat  string
+	}
+	results := map[string]result{}
+
+	for i, q := range queries {
+		for j, stat := range q.Statistics {
+			id := fmt.Sprintf("m%d_%d", i, j)
+			results[id] = result{query: i, stat: *stat}
+			input.MetricDataQueries = append(input.MetricDataQueries, &cloudwatch.MetricDataQuery{
+				Id: aws.String(id),
+				MetricStat: &cloudwatch.MetricStat{
+					Metric: &cloudwatch.Metric{
+						Namespace:  aws.String(q.Namespace),
+						MetricName: q.Name,
+						Dimensions: q.Dimensions,
+					},
+					Period: aws.Int64(PeriodInMinutes * 60),
+					Stat:   stat,
+					Unit:   q.Unit,
+				},
+			})
+		}
+	}
+
+	err := client.GetMetricDataPages(input, func(resp *cloudwatch.GetMetricDataOutput, last bool) bool {
+		for _, r := range resp.MetricDataResults {
+			res, exists := results[aws.StringValue(r.Id)]
+			if !exists || len(r.Values) == 0 || points[res.query] != nil && datapointStat(points[res.query], res.stat) != nil {
+				continue
+			}
+			if points[res.query] == nil {
+				points[res.query] = &cloudwatch.Datapoint{Unit: queries[res.query].Unit}
+				if len(r.Timestamps) > 0 {
+					points[res.query].Timestamp = r.Timestamps[0]
+				}
+			}
+			setDatapointStat(points[res.query], res.stat, aws.Float64Value(r.Values[0]))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, point := range points {
+		if point != nil {
+			queries[i].processor(point)
+		}
+	}
+
+	return nil
+
+}
+
+func datapointStat(point *cloudwatch.Datapoint, stat string) *float64 {
+	switch stat {
+	case cloudwatch.StatisticAverage:
+		return point.Average
+	case cloudwatch.StatisticSum:
+		return point.Sum
+	case cloudwatch.StatisticMinimum:
+		return point.Minimum
+	case cloudwatch.StatisticMaximum:
+		return point.Maximum
+	case cloudwatch.StatisticSampleCount:
+		return point.SampleCount
+	}
+	return nil
+}
+
+func setDatapointStat(point *cloudwatch.Datapoint, stat string, value float64) {
+	switch stat {
+	case cloudwatch.StatisticAverage:
+		point.Average = aws.Float64(value)
+	case cloudwatch.StatisticSum:
+		point.Sum = aws.Float64(value)
+	case cloudwatch.StatisticMinimum:
+		point.Minimum = aws.Float64(value)
+	case cloudwatch.StatisticMaximum:
+		point.Maximum = aws.Float64(value)
+	case cloudwatch.StatisticSampleCount:
+		point.SampleCount = aws.Float64(value)
+	}
+}
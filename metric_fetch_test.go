@@ -0,0 +1,84 @@
+package window
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+func TestMetricFetcher(t *testing.T) {
+
+	client := &fakeCloudWatch{Values: map[string]float64{"CPUUtilization:Maximum": 90}}
+	throttle := NewThrottle(10, 1000, time.Second)
+	defer throttle.stop()
+
+	var (
+		fetcher MetricFetcher
+		points  = make([]*cloudwatch.Datapoint, 400)
+	)
+	for i := range points {
+		i := i
+		fetcher.Add(&MetricQuery{
+			Namespace:  "AWS/EC2",
+			Name:       aws.String("CPUUtilization"),
+			Statistics: []*string{aws.String("Average"), aws.String("Maximum")},
+			processor:  func(point *cloudwatch.Datapoint) { points[i] = point },
+		})
+	}
+
+	for _, errc := range fetcher.Fetch(client, throttle) {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 800 statistics, with each query's statistics kept together.
+	// the requests run concurrently so may be made in either order.
+	sort.Ints(client.MetricDataCalls)
+	if expected := []int{300, 500}; !reflect.DeepEqual(client.MetricDataCalls, expected) {
+		t.Errorf("Expected requests of %v, got %v", expected, client.MetricDataCalls)
+	}
+
+	for i, point := range points {
+		if point == nil || point.Average != nil || aws.Float64Value(point.Maximum) != 90 {
+			t.Fatalf("%d: unexpected datapoint %v", i, point)
+		}
+	}
+
+}
+
+func TestRegionRefreshFetchesMetrics(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	client := region.Clients.CloudWatch.(*fakeCloudWatch)
+	client.Values = map[string]float64{
+		"RequestCount:Sum": 600,
+		"Latency:Average":  0.25,
+	}
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.MetricDataCalls) != 1 {
+		t.Errorf("Expected 1 GetMetricData request, got %v", client.MetricDataCalls)
+	}
+
+	stats := region.ELBs[0].Stats
+	if stats.RequestsPerSecond != 1 {
+		t.Errorf("Expected 1 request per second, got %v", stats.RequestsPerSecond)
+	}
+	if stats.Latency.Avg != 250*time.Millisecond {
+		t.Errorf("Expected 250ms latency, got %v", stats.Latency.Avg)
+	}
+	if stats.Latency.Min != 0 {
+		t.Errorf("Expected no min latency, got %v", stats.Latency.Min)
+	}
+
+}
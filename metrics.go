@@ -0,0 +1,385 @@
+package window
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+type (
+	// Sample is a single value of a metric in the prometheus text format.
+	// Metrics named *_total are counters, all others are gauges.
+	Sample struct {
+		Name   string
+		Labels map[string]string
+		Value  float64
+	}
+
+	SampleByNameAsc []*Sample
+)
+
+const MetricPrefix = "window_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Samples returns the collected stats and defined metrics of the region's
+// resources, labeled with the resource's name, id, vpc and az and any of
+// the given tags.
+// The caller must hold the region's lock.
+func (region *Region) Samples(tags []string) []*Sample {
+
+	var samples []*Sample
+
+	keys := make([]string, 0, len(region.Items))
+	for key := range region.Items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		v := region.Items[key]
+		prefix := MetricPrefix + snakeCase(resourceKind(v)) + "_"
+		labels := region.resourceLabels(v, tags)
+		stats_start := len(samples)
+
+		switch r := v.(type) {
+		case *ELB:
+			if r.Stats != nil {
+				samples = appendStatSamples(samples, prefix, labels, reflect.ValueOf(r.Stats))
+			}
+		case *DBInstance:
+			if r.Stats != nil {
+				samples = appendStatSamples(samples, prefix, labels, reflect.ValueOf(r.Stats))
+			}
+		case *ElasticCacheCluster:
+			for _, stats := range r.Stats {
+				node_labels := labels
+				if stats.Node != nil {
+					node_labels = copyLabels(labels)
+					node_labels["node"] = aws.StringValue(stats.Node.CacheNodeId)
+				}
+				samples = appendStatSamples(samples, prefix, node_labels, reflect.ValueOf(stats))
+			}
+		case *SQSQueue:
+			if r.Stats != nil {
+				samples = appendStatSamples(samples, prefix, labels, reflect.ValueOf(r.Stats))
+			}
+		case *SNSTopic:
+			if r.Stats != nil {
+				samples = appendStatSamples(samples, prefix, labels, reflect.ValueOf(r.Stats))
+			}
+		case *LambdaFunction:
+			if r.Stats != nil {
+				samples = appendStatSamples(samples, prefix, labels, reflect.ValueOf(r.Stats))
+			}
+		case *Instance:
+			r.sysInfo_me.RLock()
+			if r.Stats != nil {
+				samples = appendStatSamples(samples, prefix, labels, reflect.ValueOf(r.Stats))
+			}
+			r.sysInfo_me.RUnlock()
+		}
+
+		// user defined metrics, see MetricDefinition.  Metrics also
+		// holds the history of the stats sampled above.
+		stat_names := map[string]bool{}
+		for _, s := range samples[stats_start:] {
+			stat_names[s.Name] = true
+		}
+		for name, series := range resourceMetrics(v) {
+			name = prefix + snakeCase(labelName(name))
+			if p := series.Last(); p != nil && !stat_names[name] {
+				samples = append(samples, &Sample{Name: name, Labels: labels, Value: p.Value})
+			}
+		}
+
+	}
+
+	return samples
+
+}
+
+// HealthSamples returns window's own health for the region: refresh
+// and loader timings and the depth of the api/ssh throttle queue.
+// The caller must hold the region's lock.
+func (region *Region) HealthSamples() []*Sample {
+
+	labels := map[string]string{"region": region.Key()}
+	samples := []*Sample{
+		{Name: MetricPrefix + "refresh_duration_seconds", Labels: labels, Value: region.RefreshDuration.Seconds()},
+		{Name: MetricPrefix + "refresh_timestamp_seconds", Labels: labels, Value: unixSeconds(region.Refreshed)},
+	}
+
+	for _, status := range region.LoaderStatuses() {
+		loader_labels := copyLabels(labels)
+		loader_labels["loader"] = status.Name
+		var failing float64
+		if status.Failing() {
+			failing = 1
+		}
+		samples = append(samples,
+			&Sample{Name: MetricPrefix + "loader_duration_seconds", Labels: loader_labels, Value: status.Duration.Seconds()},
+			&Sample{Name: MetricPrefix + "loader_last_success_timestamp_seconds", Labels: loader_labels, Value: unixSeconds(status.LastSuccess)},
+			&Sample{Name: MetricPrefix + "loader_failing", Labels: loader_labels, Value: failing},
+		)
+	}
+
+	if region.Throttle != nil {
+		samples = append(samples,
+			&Sample{Name: MetricPrefix + "throttle_queued", Labels: labels, Value: float64(region.Throttle.Queued())},
+			&Sample{Name: MetricPrefix + "throttle_running", Labels: labels, Value: float64(region.Throttle.Running())},
+		)
+	}
+
+	return samples
+
+}
+
+// APICallSamples returns the number of aws api calls made per service
+func APICallSamples() []*Sample {
+	var samples []*Sample
+	for service, ct := range APICalls() {
+		samples = append(samples, &Sample{
+			Name:   MetricPrefix + "aws_api_calls_total",
+			Labels: map[string]string{"service": service},
+			Value:  float64(ct),
+		})
+	}
+	return samples
+}
+
+// WriteSamples writes samples in the prometheus text exposition format,
+// grouped by metric name.
+func WriteSamples(w io.Writer, samples []*Sample) error {
+
+	sorted := append([]*Sample(nil), samples...)
+	sort.Stable(SampleByNameAsc(sorted))
+
+	bw := bufio.NewWriter(w)
+
+	for i, s := range sorted {
+		if i == 0 || sorted[i-1].Name != s.Name {
+			typ := "gauge"
+			if strings.HasSuffix(s.Name, "_total") {
+				typ = "counter"
+			}
+			fmt.Fprintf(bw, "# TYPE %s %s\n", s.Name, typ)
+		}
+		bw.WriteString(s.Name)
+		if len(s.Labels) > 0 {
+			names := make([]string, 0, len(s.Labels))
+			for name := range s.Labels {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			bw.WriteByte('{')
+			for j, name := range names {
+				if j > 0 {
+					bw.WriteByte(',')
+				}
+				fmt.Fprintf(bw, "%s=\"%s\"", name, escapeLabelValue(s.Labels[name]))
+			}
+			bw.WriteByte('}')
+		}
+		bw.WriteByte(' ')
+		bw.WriteString(formatSampleValue(s.Value))
+		bw.WriteByte('\n')
+	}
+
+	return bw.Flush()
+
+}
+
+func (a SampleByNameAsc) Len() int           { return len(a) }
+func (a SampleByNameAsc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a SampleByNameAsc) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+func (region *Region) resourceLabels(v interface{}, tags []string) map[string]string {
+
+	labels := map[string]string{
+		"region": region.Key(),
+		"name":   resourceString(v, "Name"),
+		"id":     resourceString(v, "Id"),
+	}
+
+	// resourceVPCs gives names as well as ids
+	var vpcs []string
+	for _, vpc := range resourceVPCs(v) {
+		if strings.HasPrefix(vpc, "vpc-") {
+			vpcs = appendUnique(vpcs, vpc)
+		}
+	}
+	if len(vpcs) > 0 {
+		labels["vpc"] = strings.Join(vpcs, ",")
+	}
+
+	var azs []string
+	for _, az := range resourceAZs(v) {
+		azs = appendUnique(azs, az)
+	}
+	if len(azs) > 0 {
+		labels["az"] = strings.Join(azs, ",")
+	}
+
+	if len(tags) > 0 {
+		for _, pair := range strings.Split(resourceTags(v), ",") {
+			i := strings.IndexByte(pair, '=')
+			if i < 0 {
+				continue
+			}
+			for _, tag := range tags {
+				if pair[:i] == tag {
+					labels["tag_"+labelName(tag)] = pair[i+1:]
+				}
+			}
+		}
+	}
+
+	return labels
+
+}
+
+// appendStatSamples flattens the numeric fields of a stats struct into
+// samples named prefix + the snake cased field path.  Durations are
+// given in seconds, bools as 0 or 1.  Links, slices and aws sdk
+// structs are skipped.
+func appendStatSamples(samples []*Sample, prefix string, labels map[string]string, v reflect.Value) []*Sample {
+
+	v = reflect.Indirect(v)
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return samples
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := prefix + snakeCase(f.Name)
+		field := v.Field(i)
+		switch {
+		case f.Type == durationType:
+			samples = append(samples, &Sample{Name: name + "_seconds", Labels: labels, Value: time.Duration(field.Int()).Seconds()})
+		case linkTypes[f.Type]:
+		case isStatsStruct(f.Type):
+			if f.Type.Kind() == reflect.Ptr && field.IsNil() {
+				continue
+			}
+			if f.Anonymous {
+				samples = appendStatSamples(samples, prefix, labels, field)
+			} else {
+				samples = appendStatSamples(samples, name+"_", labels, field)
+			}
+		case f.Type.Kind() == reflect.Bool:
+			var b float64
+			if field.Bool() {
+				b = 1
+			}
+			samples = append(samples, &Sample{Name: name, Labels: labels, Value: b})
+		case f.Type.Kind() >= reflect.Int && f.Type.Kind() <= reflect.Int64:
+			samples = append(samples, &Sample{Name: name, Labels: labels, Value: float64(field.Int())})
+		case f.Type.Kind() >= reflect.Uint && f.Type.Kind() <= reflect.Uint64:
+			samples = append(samples, &Sample{Name: name, Labels: labels, Value: float64(field.Uint())})
+		case f.Type.Kind() == reflect.Float32, f.Type.Kind() == reflect.Float64:
+			samples = append(samples, &Sample{Name: name, Labels: labels, Value: field.Float()})
+		}
+	}
+
+	return samples
+
+}
+
+// isStatsStruct reports whether t is a struct, or pointer to one, that is
+// declared inline or in this repository, as opposed to sdk or time types
+func isStatsStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	return len(t.Name()) == 0 || strings.HasPrefix(t.PkgPath(), "github.com/emptyinterface/window")
+}
+
+// snakeCase converts a go name to a metric name, e.g.
+// CPUUtilization to cpu_utilization and Code2XX to code_2xx.
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var out []rune
+	for i, r := range rs {
+		if i > 0 {
+			prev := rs[i-1]
+			switch {
+			case unicode.IsUpper(r) && unicode.IsLower(prev),
+				unicode.IsUpper(r) && unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1]),
+				unicode.IsDigit(r) && unicode.IsLetter(prev):
+				out = append(out, '_')
+			}
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
+
+// labelName replaces the characters not allowed in label names
+func labelName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatSampleValue(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	cp := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		cp[k] = v
+	}
+	return cp
+}
+
+func appendUnique(list []string, s string) []string {
+	if len(s) == 0 {
+		return list
+	}
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,98 @@
+package window
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+func TestRegionSamples(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	inst := region.Items[ItemKey(testAccountId, "inst:i-1")].(*Instance)
+	inst.Stats = &sysinfo.SystemInfoSummary{Duration: 1500 * time.Millisecond}
+	inst.Stats.CPU.PercentInUse = 0.25
+	inst.Stats.Memory.Total = 1024
+
+	var buf bytes.Buffer
+	if err := WriteSamples(&buf, region.Samples([]string{"Name"})); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	labels := `{az="us-test-1a",id="inst:i-1",name="web-1",region="` + region.Key() + `",tag_Name="web-1",vpc="vpc-1"}`
+	for _, line := range []string{
+		"# TYPE window_instance_cpu_percent_in_use gauge\n",
+		"window_instance_cpu_percent_in_use" + labels + " 0.25\n",
+		"window_instance_memory_total" + labels + " 1024\n",
+		"window_instance_duration_seconds" + labels + " 1.5\n",
+		"# TYPE window_elb_requests_per_second gauge\n",
+		"window_elb_latency_avg_seconds{",
+		"window_elb_status_per_second_code_2xx{",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in:\n%s", line, out)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteSamples(&buf, region.HealthSamples()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`window_loader_failing{loader="LoadVPCs",region="` + region.Key() + `"} 0`,
+		`window_throttle_queued{region="` + region.Key() + `"} 0`,
+		"# TYPE window_refresh_duration_seconds gauge\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected %q in:\n%s", line, buf.String())
+		}
+	}
+
+}
+
+func TestWriteSamples(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := WriteSamples(&buf, []*Sample{
+		{Name: "b_total", Labels: map[string]string{"service": "ec2"}, Value: 3},
+		{Name: "a", Labels: map[string]string{"name": "say \"hi\"\n", "id": `c:\`}, Value: 0.5},
+		{Name: "b_total", Labels: map[string]string{"service": "sqs"}, Value: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# TYPE a gauge
+a{id="c:\\",name="say \"hi\"\n"} 0.5
+# TYPE b_total counter
+b_total{service="ec2"} 3
+b_total{service="sqs"} 1
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"CPUUtilization":    "cpu_utilization",
+		"Code2XX":           "code_2xx",
+		"RequestsPerSecond": "requests_per_second",
+		"DBInstance":        "db_instance",
+		"ELB":               "elb",
+	} {
+		if s := snakeCase(name); s != expected {
+			t.Errorf("%s: Expected %q, got %q", name, expected, s)
+		}
+	}
+}
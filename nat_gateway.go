@@ -79,11 +79,12 @@ type (
 		// The ID of the VPC in which the NAT gateway is located.
 		VpcId string
 
-		Name   string
-		Id     string
-		Region *Region
-		VPC    *VPC
-		Subnet *Subnet
+		Name      string
+		Id        string
+		AccountId string
+		Region    *Region
+		VPC       *VPC
+		Subnet    *Subnet
 	}
 
 	NATGatewaysByNameAsc []*NATGateway
@@ -95,9 +96,9 @@ func (a NATGatewaysByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadNATGateways(input *ec2.DescribeNatGatewaysInput) (map[string]*NATGateway, error) {
+func LoadNATGateways(client EC2API, input *ec2.DescribeNatGatewaysInput) (map[string]*NATGateway, error) {
 
-	resp, err := EC2Client.DescribeNatGateways(input)
+	resp, err := client.DescribeNatGateways(input)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,185 @@
+package window
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	// ItemQuery selects resources from Region.Items.
+	// Empty fields match every resource.
+	ItemQuery struct {
+		// resource kind, e.g. instance or instances
+		Kind string
+		// vpc id or name
+		VPC string
+		// availability zone name
+		AZ string
+		// tag key, or key=value
+		Tag string
+		// State of the resource
+		State string
+	}
+
+	// ItemJSON is a resource encoded with its links to other
+	// resources replaced by their Items keys
+	ItemJSON struct {
+		Key  string                     `json:"key"`
+		Kind string                     `json:"kind"`
+		Item map[string]json.RawMessage `json:"item"`
+	}
+)
+
+// Kinds returns the number of items of each kind
+func (region *Region) Kinds() map[string]int {
+	kinds := map[string]int{}
+	for _, v := range region.Items {
+		kinds[resourceKind(v)]++
+	}
+	return kinds
+}
+
+// Query returns the sorted keys of the items matching q
+func (region *Region) Query(q ItemQuery) []string {
+	var keys []string
+	for key, v := range region.Items {
+		if q.Matches(v) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (q ItemQuery) Matches(v interface{}) bool {
+
+	if len(q.Kind) > 0 {
+		kind := resourceKind(v)
+		if !strings.EqualFold(q.Kind, kind) && !strings.EqualFold(q.Kind, kind+"s") {
+			return false
+		}
+	}
+
+	if len(q.State) > 0 && !strings.EqualFold(q.State, resourceString(v, "State")) {
+		return false
+	}
+
+	if len(q.Tag) > 0 {
+		var found bool
+		for _, pair := range strings.Split(resourceTags(v), ",") {
+			if pair == q.Tag || strings.HasPrefix(pair, q.Tag+"=") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.VPC) > 0 {
+		var found bool
+		for _, vpc := range resourceVPCs(v) {
+			if vpc == q.VPC {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.AZ) > 0 {
+		var found bool
+		for _, az := range resourceAZs(v) {
+			if az == q.AZ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+// resourceVPCs returns the ids and names of the vpcs a resource is in
+func resourceVPCs(v interface{}) []string {
+	if vpc, ok := v.(*VPC); ok {
+		return []string{vpc.VpcId, vpc.Name}
+	}
+	var vpcs []string
+	if id := resourceString(v, "VpcId"); len(id) > 0 {
+		vpcs = append(vpcs, id)
+	}
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if field := val.FieldByName("VPC"); field.IsValid() {
+		if vpc, ok := field.Interface().(*VPC); ok && vpc != nil {
+			vpcs = append(vpcs, vpc.VpcId, vpc.Name)
+		}
+	}
+	if field := val.FieldByName("VPCs"); field.IsValid() {
+		if list, ok := field.Interface().([]*VPC); ok {
+			for _, vpc := range list {
+				vpcs = append(vpcs, vpc.VpcId, vpc.Name)
+			}
+		}
+	}
+	return vpcs
+}
+
+// resourceAZs returns the names of the availability zones a resource is in
+func resourceAZs(v interface{}) []string {
+	if az, ok := v.(*AvailabilityZone); ok {
+		return []string{az.Name}
+	}
+	var azs []string
+	val := reflect.Indirect(reflect.ValueOf(v))
+	for _, name := range []string{"AvailabilityZoneName", "AvailabilityZone"} {
+		if name := resourceString(v, name); len(name) > 0 {
+			azs = append(azs, name)
+		}
+	}
+	if field := val.FieldByName("AvailabilityZone"); field.IsValid() {
+		if az, ok := field.Interface().(*AvailabilityZone); ok && az != nil {
+			azs = append(azs, az.Name)
+		}
+	}
+	if field := val.FieldByName("AvailabilityZones"); field.IsValid() {
+		if list, ok := field.Interface().([]*AvailabilityZone); ok {
+			for _, az := range list {
+				azs = append(azs, az.Name)
+			}
+		}
+	}
+	return azs
+}
+
+// ItemsJSON encodes the items with the given keys, skipping missing keys.
+// The caller must hold the region's lock.
+func (region *Region) ItemsJSON(keys []string) ([]*ItemJSON, error) {
+
+	enc := newSnapshotEncoder(region)
+
+	items := make([]*ItemJSON, 0, len(keys))
+	for _, key := range keys {
+		v, exists := region.Items[key]
+		if !exists {
+			continue
+		}
+		fields, err := enc.fields(reflect.ValueOf(v).Elem())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &ItemJSON{Key: key, Kind: resourceKind(v), Item: fields})
+	}
+
+	return items, nil
+
+}
@@ -0,0 +1,73 @@
+package window
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRegionQuery(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	key := func(id string) string { return ItemKey(testAccountId, id) }
+
+	for _, test := range []struct {
+		query    ItemQuery
+		expected []string
+	}{
+		{ItemQuery{Kind: "instances"}, []string{key("inst:i-1"), key("inst:i-2")}},
+		{ItemQuery{Kind: "Instance", State: "stopped"}, []string{key("inst:i-2")}},
+		{ItemQuery{Kind: "instance", VPC: "main"}, []string{key("inst:i-1")}},
+		{ItemQuery{Kind: "instance", VPC: "vpc-1"}, []string{key("inst:i-1")}},
+		{ItemQuery{Kind: "instance", AZ: "us-test-1b"}, []string{key("inst:i-2")}},
+		{ItemQuery{Kind: "vpc", Tag: "Name=main"}, []string{key("vpc-1")}},
+		{ItemQuery{Kind: "vpc", Tag: "Name"}, []string{key("vpc-1")}},
+		{ItemQuery{Kind: "vpc", Tag: "Name=other"}, nil},
+	} {
+		if keys := region.Query(test.query); !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("%+v: Expected %v, got %v", test.query, test.expected, keys)
+		}
+	}
+
+}
+
+func TestRegionItemsJSON(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := region.ItemsJSON([]string{ItemKey(testAccountId, "inst:i-1"), "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Kind != "Instance" {
+		t.Fatalf("unexpected items: %v", items)
+	}
+
+	var vpc string
+	if err := json.Unmarshal(items[0].Item["VPC"], &vpc); err != nil {
+		t.Fatal(err)
+	}
+	if vpc != ItemKey(testAccountId, "vpc-1") {
+		t.Errorf("Expected vpc link as key, got %q", vpc)
+	}
+
+	var groups []string
+	if err := json.Unmarshal(items[0].Item["SecurityGroups"], &groups); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups, []string{ItemKey(testAccountId, "sg:sg-1")}) {
+		t.Errorf("Expected security group links as keys, got %v", groups)
+	}
+
+}
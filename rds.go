@@ -184,6 +184,7 @@ type (
 
 		Name             string
 		Id               string
+		AccountId        string
 		State            string
 		Region           *Region
 		Classic          *Classic
@@ -195,6 +196,7 @@ type (
 
 		MemoryCapacity int64
 		Stats          *DBInstanceStats
+		Metrics        map[string]*Series
 
 		Log []string
 	}
@@ -219,11 +221,11 @@ func (a DescribeLogsDetailsByLastWrittenDesc) Less(i, j int) bool {
 	return aws.Int64Value(a[i].LastWritten) > aws.Int64Value(a[j].LastWritten)
 }
 
-func LoadDBInstances(input *rds.DescribeDBInstancesInput) (map[string]*DBInstance, error) {
+func LoadDBInstances(client RDSAPI, input *rds.DescribeDBInstancesInput) (map[string]*DBInstance, error) {
 
 	dbs := map[string]*DBInstance{}
 
-	if err := RDSClient.DescribeDBInstancesPages(input, func(page *rds.DescribeDBInstancesOutput, _ bool) bool {
+	if err := client.DescribeDBInstancesPages(input, func(page *rds.DescribeDBInstancesOutput, _ bool) bool {
 		for _, rdsDbinst := range page.DBInstances {
 			dbinst := &DBInstance{
 				AllocatedStorage:                      aws.Int64Value(rdsDbinst.AllocatedStorage),
@@ -339,14 +341,14 @@ func (dbinst *DBInstance) MonthlyCost() float64 {
 	return 0
 }
 
-func (db *DBInstance) Poll() []chan error {
+func (db *DBInstance) Poll(metrics *MetricFetcher) []chan error {
 
 	var errs []chan error
 
 	db.Stats = &DBInstanceStats{}
 
 	errs = append(errs, db.Region.Throttle.do(db.Name+" LOG POLL", func() error {
-		resp, err := RDSClient.DescribeDBLogFiles(&rds.DescribeDBLogFilesInput{
+		resp, err := db.Region.Clients.RDS.DescribeDBLogFiles(&rds.DescribeDBLogFilesInput{
 			DBInstanceIdentifier: aws.String(db.DBInstanceIdentifier),
 			FileSize:             aws.Int64(1),
 		})
@@ -360,7 +362,7 @@ func (db *DBInstance) Poll() []chan error {
 		for len(db.Log) < RDSMaxLogLines && len(resp.DescribeDBLogFiles) > 0 {
 			details := resp.DescribeDBLogFiles[0]
 			resp.DescribeDBLogFiles = resp.DescribeDBLogFiles[1:]
-			resp, err := RDSClient.DownloadDBLogFilePortion(&rds.DownloadDBLogFilePortionInput{
+			resp, err := db.Region.Clients.RDS.DownloadDBLogFilePortion(&rds.DownloadDBLogFilePortionInput{
 				DBInstanceIdentifier: aws.String(db.DBInstanceIdentifier),
 				LogFileName:          details.LogFileName,
 				NumberOfLines:        aws.Int64(RDSMaxLogLines - int64(len(db.Log))),
@@ -379,10 +381,7 @@ func (db *DBInstance) Poll() []chan error {
 	}))
 
 	for _, m := range RDSMetrics {
-		m := m
-		errs = append(errs, db.Region.Throttle.do(db.Name+" METRICS POLL", func() error {
-			return m.RunFor(db)
-		}))
+		metrics.Add(m.Query(db))
 	}
 
 	return errs
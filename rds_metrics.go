@@ -206,25 +206,18 @@ var (
 	}
 )
 
-func (m *rdsmetric) RunFor(db *DBInstance) error {
-
-	resp, err := CloudWatchClient.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
-		StartTime: aws.Time(time.Now().Add(-PeriodInMinutes * time.Minute)),
-		EndTime:   aws.Time(time.Now()),
-		Period:    aws.Int64(PeriodInMinutes * 60),
-		Namespace: aws.String("AWS/RDS"),
+func (m *rdsmetric) Query(db *DBInstance) *MetricQuery {
+	return &MetricQuery{
+		Namespace: "AWS/RDS",
+		Name:      m.name,
 		Dimensions: []*cloudwatch.Dimension{{
 			Name:  aws.String("DBInstanceIdentifier"),
 			Value: aws.String(db.DBInstanceIdentifier),
 		}},
-		MetricName: m.name,
 		Statistics: m.statistics,
 		Unit:       m.unit, // fuck this in teh face
-	})
-	if err == nil && len(resp.Datapoints) > 0 {
-		m.processor(db, resp.Datapoints[0])
+		processor: func(point *cloudwatch.Datapoint) {
+			m.processor(db, point)
+		},
 	}
-
-	return err
-
 }
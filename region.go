@@ -14,8 +14,9 @@ import (
 
 type (
 	Region struct {
-		*sync.Mutex
+		*sync.Mutex `json:"-"`
 
+		Account *Account
 		Name    string
 		Classic *Classic
 		VPCs    []*VPC
@@ -45,70 +46,169 @@ type (
 		// location of pem files corresponding to ec2 key names
 		sshKeyPath string
 
-		Items map[string]interface{}
+		Items map[string]interface{} `json:"-"`
 
-		Throttle *throttle
+		// status of each loader as of the last Refresh
+		Loaders map[string]*LoaderStatus
+
+		// results of the last Refresh, reused for loaders that fail
+		loaded *loaded
+
+		// time of the last Refresh, and how long it took
+		Refreshed       time.Time
+		RefreshDuration time.Duration
+
+		// changes found by each Refresh are published here
+		Events *EventLog `json:"-"`
+
+		// cloudwatch metrics collected into each resource's Metrics
+		MetricDefinitions []*MetricDefinition `json:"-"`
+
+		// how much of each resource's Metrics is kept across refreshes
+		History History `json:"-"`
+
+		Clients  *Clients  `json:"-"`
+		Throttle *throttle `json:"-"`
+
+		// polls instance sysinfo over ssh
+		Poller *InstancePoller `json:"-"`
 	}
 )
 
-func NewRegion(name string) *Region {
+func NewRegion(account *Account, name string) (*Region, error) {
+	table, _ := pricing.LoadTable()
+	return newPricedRegion(account, name, table)
+}
+
+func newPricedRegion(account *Account, name string, table *pricing.Table) (*Region, error) {
+	sess, err := account.Session(name)
+	if err != nil {
+		return nil, err
+	}
+	r := newRegion(name)
+	r.Account = account
+	r.Clients = NewClients(sess)
+	r.Throttle = NewThrottle(1, 100, time.Second)
+	r.Poller = NewInstancePoller(DefaultSSHSessions)
+	if table != nil {
+		r.LoadPrices(table)
+	}
+	return r, nil
+}
+
+func newRegion(name string) *Region {
 	r := &Region{}
 	r.Mutex = &sync.Mutex{}
 	r.Name = name
 	r.Classic = &Classic{}
-	r.Throttle = NewThrottle(1, 100, time.Second)
 	r.Prices = map[string]*pricing.Row{}
 	r.Items = map[string]interface{}{}
+	r.Loaders = map[string]*LoaderStatus{}
+	r.loaded = &loaded{}
+	r.History = DefaultHistory
+	return r
+}
 
-	if table, err := pricing.LoadTable(); err == nil {
-		for _, row := range table.Rows {
-			if row.Region != r.Name {
-				continue
+// LoadPrices indexes the rows of the pricing table that apply to this region
+func (r *Region) LoadPrices(table *pricing.Table) {
+	for _, row := range table.Rows {
+		if row.Region != r.Name {
+			continue
+		}
+		switch row.OfferCode {
+		case pricing.AmazonEC2OfferCode:
+			if len(row.InstanceType) > 0 {
+				key := fmt.Sprintf("%s:%s:%s:%s:%s",
+					row.OfferCode,
+					row.TermType,
+					row.Tenancy,
+					row.InstanceType,
+					row.OperatingSystem,
+				)
+				r.Prices[key] = row
 			}
-			switch row.OfferCode {
-			case pricing.AmazonEC2OfferCode:
-				if len(row.InstanceType) > 0 {
-					key := fmt.Sprintf("%s:%s:%s:%s:%s",
-						row.OfferCode,
-						row.TermType,
-						row.Tenancy,
-						row.InstanceType,
-						row.OperatingSystem,
-					)
-					r.Prices[key] = row
-				}
-			case pricing.AmazonRDSOfferCode:
-				if len(row.InstanceType) > 0 {
-					key := fmt.Sprintf("%s:%s:%s:%s:%s",
-						row.OfferCode,
-						row.TermType,
-						row.DeploymentOption,
-						row.InstanceType,
-						row.DatabaseEngine,
-					)
-					r.Prices[key] = row
-				}
-			case pricing.AmazonElastiCacheOfferCode:
-				if len(row.InstanceType) > 0 {
-					key := fmt.Sprintf("%s:%s:%s:%s",
-						row.OfferCode,
-						row.TermType,
-						row.InstanceType,
-						row.CacheEngine,
-					)
-					r.Prices[key] = row
-				}
+		case pricing.AmazonRDSOfferCode:
+			if len(row.InstanceType) > 0 {
+				key := fmt.Sprintf("%s:%s:%s:%s:%s",
+					row.OfferCode,
+					row.TermType,
+					row.DeploymentOption,
+					row.InstanceType,
+					row.DatabaseEngine,
+				)
+				r.Prices[key] = row
+			}
+		case pricing.AmazonElastiCacheOfferCode:
+			if len(row.InstanceType) > 0 {
+				key := fmt.Sprintf("%s:%s:%s:%s",
+					row.OfferCode,
+					row.TermType,
+					row.InstanceType,
+					row.CacheEngine,
+				)
+				r.Prices[key] = row
 			}
 		}
 	}
+}
 
-	return r
+// Key uniquely identifies the region within a Fleet
+func (region *Region) Key() string {
+	if region.Account == nil || len(region.Account.Name) == 0 {
+		return region.Name
+	}
+	return region.Account.Name + ":" + region.Name
 }
 
 func (region *Region) SetSSHKeyPath(path string) {
 	region.sshKeyPath = path
 }
 
+// LoaderStatuses returns the status of each loader sorted by name
+func (region *Region) LoaderStatuses() []*LoaderStatus {
+	statuses := make([]*LoaderStatus, 0, len(region.Loaders))
+	for _, status := range region.Loaders {
+		statuses = append(statuses, status)
+	}
+	sort.Sort(LoaderStatusByNameAsc(statuses))
+	return statuses
+}
+
+// FailingLoaders returns the loaders that failed in the last Refresh
+func (region *Region) FailingLoaders() []*LoaderStatus {
+	var failing []*LoaderStatus
+	for _, status := range region.LoaderStatuses() {
+		if status.Failing() {
+			failing = append(failing, status)
+		}
+	}
+	return failing
+}
+
+func (region *Region) MonthlyCost() float64 {
+	var total float64
+	for _, inst := range region.Instances {
+		total += inst.MonthlyCost()
+	}
+	for _, dbinst := range region.DBInstances {
+		total += dbinst.MonthlyCost()
+	}
+	for _, ecc := range region.ElasticCacheClusters {
+		total += ecc.MonthlyCost()
+	}
+	return total
+}
+
+func (region *Region) AlarmsInState(state string) []*CloudWatchAlarm {
+	var alarms []*CloudWatchAlarm
+	for _, alarm := range region.CloudWatchAlarms {
+		if alarm.StateValue == state {
+			alarms = append(alarms, alarm)
+		}
+	}
+	return alarms
+}
+
 func (region *Region) Refresh() error {
 
 	var (
@@ -141,8 +241,56 @@ func (region *Region) Refresh() error {
 		errs []chan error
 	)
 
-	errs = append(errs, region.Throttle.do("LoadInstances", func() (err error) {
-		instances, err = LoadInstances(nil)
+	refresh_start := time.Now()
+
+	accountId, err := region.Account.ResolveId(region.Clients.STS)
+	if err != nil {
+		return err
+	}
+
+	prev := region.loaded
+
+	// statuses are copied so the current ones can be read while loading
+	var statuses_me sync.Mutex
+	statuses := map[string]*LoaderStatus{}
+	for name, status := range region.Loaders {
+		s := *status
+		statuses[name] = &s
+	}
+
+	// load runs a loader through the throttle, recording its status.
+	// loaders that fail fall back to the results of the previous refresh.
+	load := func(name string, f func() error) chan error {
+		return region.Throttle.do(name, func() error {
+			start := time.Now()
+			err := f()
+			statuses_me.Lock()
+			defer statuses_me.Unlock()
+			status, exists := statuses[name]
+			if !exists {
+				status = &LoaderStatus{Name: name}
+				statuses[name] = status
+			}
+			status.LastAttempt = start
+			status.Duration = time.Since(start)
+			if err != nil {
+				status.LastError = err.Error()
+			} else {
+				status.LastError = ""
+				status.LastSuccess = start
+			}
+			return err
+		})
+	}
+
+	errs = append(errs, load("LoadInstances", func() (err error) {
+		defer func() {
+			if err != nil {
+				instances = unlinked(prev.instances).(map[string]*Instance)
+				amis = unlinked(prev.amis).(map[string]*AMI)
+			}
+		}()
+		instances, err = LoadInstances(region.Clients.EC2, nil)
 		if err != nil {
 			return
 		}
@@ -155,108 +303,155 @@ func (region *Region) Refresh() error {
 			for imageId, _ := range imageIds {
 				input.ImageIds = append(input.ImageIds, aws.String(imageId))
 			}
-			amis, err = LoadAMIs(input)
+			amis, err = LoadAMIs(region.Clients.EC2, input)
 		} else {
 			amis = map[string]*AMI{}
 		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadCacheClusters", func() (err error) {
-		ec_clusters, err = LoadCacheClusters(nil)
+	errs = append(errs, load("LoadCacheClusters", func() (err error) {
+		if ec_clusters, err = LoadCacheClusters(region.Clients.ElastiCache, nil); err != nil {
+			ec_clusters = unlinked(prev.ec_clusters).(map[string]*ElasticCacheCluster)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadDBInstances", func() (err error) {
-		db_instances, err = LoadDBInstances(nil)
+	errs = append(errs, load("LoadDBInstances", func() (err error) {
+		if db_instances, err = LoadDBInstances(region.Clients.RDS, nil); err != nil {
+			db_instances = unlinked(prev.db_instances).(map[string]*DBInstance)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadSecurityGroups", func() (err error) {
-		security_groups, err = LoadSecurityGroups(nil)
+	errs = append(errs, load("LoadSecurityGroups", func() (err error) {
+		if security_groups, err = LoadSecurityGroups(region.Clients.EC2, nil); err != nil {
+			security_groups = unlinked(prev.security_groups).(map[string]*SecurityGroup)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadVPCs", func() (err error) {
-		vpcs, err = LoadVPCs(nil)
+	errs = append(errs, load("LoadVPCs", func() (err error) {
+		if vpcs, err = LoadVPCs(region.Clients.EC2, nil); err != nil {
+			vpcs = unlinked(prev.vpcs).(map[string]*VPC)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadInternetGateways", func() (err error) {
-		internet_gateways, err = LoadInternetGateways(nil)
+	errs = append(errs, load("LoadInternetGateways", func() (err error) {
+		if internet_gateways, err = LoadInternetGateways(region.Clients.EC2, nil); err != nil {
+			internet_gateways = unlinked(prev.internet_gateways).(map[string]*InternetGateway)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadCustomerGateways", func() (err error) {
-		customer_gateways, err = LoadCustomerGateways(nil)
+	errs = append(errs, load("LoadCustomerGateways", func() (err error) {
+		if customer_gateways, err = LoadCustomerGateways(region.Clients.EC2, nil); err != nil {
+			customer_gateways = unlinked(prev.customer_gateways).(map[string]*CustomerGateway)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadVPGateways", func() (err error) {
-		vp_gateways, err = LoadVPGateways(nil)
+	errs = append(errs, load("LoadVPGateways", func() (err error) {
+		if vp_gateways, err = LoadVPGateways(region.Clients.EC2, nil); err != nil {
+			vp_gateways = unlinked(prev.vp_gateways).(map[string]*VPGateway)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadVPNConnections", func() (err error) {
-		vpn_connections, err = LoadVPNConnections(nil)
+	errs = append(errs, load("LoadVPNConnections", func() (err error) {
+		if vpn_connections, err = LoadVPNConnections(region.Clients.EC2, nil); err != nil {
+			vpn_connections = unlinked(prev.vpn_connections).(map[string]*VPNConnection)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadAvailabilityZones", func() (err error) {
-		availability_zones, err = LoadAvailabilityZones(nil)
+	errs = append(errs, load("LoadAvailabilityZones", func() (err error) {
+		if availability_zones, err = LoadAvailabilityZones(region.Clients.EC2, nil); err != nil {
+			availability_zones = unlinked(prev.availability_zones).(map[string]*AvailabilityZone)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadACLs", func() (err error) {
-		acls, err = LoadACLs(nil)
+	errs = append(errs, load("LoadACLs", func() (err error) {
+		if acls, err = LoadACLs(region.Clients.EC2, nil); err != nil {
+			acls = unlinked(prev.acls).(map[string]*ACL)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadRouteTables", func() (err error) {
-		route_tables, err = LoadRouteTables(nil)
+	errs = append(errs, load("LoadRouteTables", func() (err error) {
+		if route_tables, err = LoadRouteTables(region.Clients.EC2, nil); err != nil {
+			route_tables = unlinked(prev.route_tables).(map[string]*RouteTable)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadSubnets", func() (err error) {
-		subnets, err = LoadSubnets(nil)
+	errs = append(errs, load("LoadSubnets", func() (err error) {
+		if subnets, err = LoadSubnets(region.Clients.EC2, nil); err != nil {
+			subnets = unlinked(prev.subnets).(map[string]*Subnet)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadELBs", func() (err error) {
-		elbs, err = LoadELBs(nil)
+	errs = append(errs, load("LoadELBs", func() (err error) {
+		if elbs, err = LoadELBs(region.Clients.ELB, nil); err != nil {
+			elbs = unlinked(prev.elbs).(map[string]*ELB)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadAutoScalingGroups", func() (err error) {
-		as_groups, err = LoadAutoScalingGroups(nil)
+	errs = append(errs, load("LoadAutoScalingGroups", func() (err error) {
+		if as_groups, err = LoadAutoScalingGroups(region.Clients.AutoScaling, nil); err != nil {
+			as_groups = unlinked(prev.as_groups).(map[string]*AutoScalingGroup)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadVPCEndpoints", func() (err error) {
-		vpc_endpoints, err = LoadVPCEndpoints(nil)
+	errs = append(errs, load("LoadVPCEndpoints", func() (err error) {
+		if vpc_endpoints, err = LoadVPCEndpoints(region.Clients.EC2, nil); err != nil {
+			vpc_endpoints = unlinked(prev.vpc_endpoints).(map[string]*VPCEndpoint)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadVPCPeeringConnections", func() (err error) {
-		vpc_peering_connections, err = LoadVPCPeeringConnections(nil)
+	errs = append(errs, load("LoadVPCPeeringConnections", func() (err error) {
+		if vpc_peering_connections, err = LoadVPCPeeringConnections(region.Clients.EC2, nil); err != nil {
+			vpc_peering_connections = unlinked(prev.vpc_peering_connections).(map[string]*VPCPeeringConnection)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadSQSQueues", func() (err error) {
-		sqs_queues, err = LoadSQSQueues(nil)
+	errs = append(errs, load("LoadSQSQueues", func() (err error) {
+		if sqs_queues, err = LoadSQSQueues(region.Clients.SQS, nil); err != nil {
+			sqs_queues = unlinked(prev.sqs_queues).(map[string]*SQSQueue)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadSNSTopics", func() (err error) {
-		sns_topics, err = LoadSNSTopics(nil)
+	errs = append(errs, load("LoadSNSTopics", func() (err error) {
+		if sns_topics, err = LoadSNSTopics(region.Clients.SNS, nil); err != nil {
+			sns_topics = unlinked(prev.sns_topics).(map[string]*SNSTopic)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadSNSSubscriptions", func() (err error) {
-		sns_subscribers, err = LoadSNSSubscriptions(nil)
+	errs = append(errs, load("LoadSNSSubscriptions", func() (err error) {
+		if sns_subscribers, err = LoadSNSSubscriptions(region.Clients.SNS, nil); err != nil {
+			sns_subscribers = unlinked(prev.sns_subscribers).(map[string]*SNSSubscription)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadCloudWatchAlarms", func() (err error) {
-		cloudwatch_alarms, err = LoadCloudWatchAlarms(nil)
+	errs = append(errs, load("LoadCloudWatchAlarms", func() (err error) {
+		if cloudwatch_alarms, err = LoadCloudWatchAlarms(region.Clients.CloudWatch, nil); err != nil {
+			cloudwatch_alarms = unlinked(prev.cloudwatch_alarms).(map[string]*CloudWatchAlarm)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadLambdaFunctions", func() (err error) {
-		lambda_functions, err = LoadLambdaFunctions(nil)
+	errs = append(errs, load("LoadLambdaFunctions", func() (err error) {
+		if lambda_functions, err = LoadLambdaFunctions(region.Clients.Lambda, nil); err != nil {
+			lambda_functions = unlinked(prev.lambda_functions).(map[string]*LambdaFunction)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadENIs", func() (err error) {
-		enis, err = LoadENIs(nil)
+	errs = append(errs, load("LoadENIs", func() (err error) {
+		if enis, err = LoadENIs(region.Clients.EC2, nil); err != nil {
+			enis = unlinked(prev.enis).(map[string]*ENI)
+		}
 		return
 	}))
-	errs = append(errs, region.Throttle.do("LoadNATGateways", func() (err error) {
-		nat_gateways, err = LoadNATGateways(nil)
+	errs = append(errs, load("LoadNATGateways", func() (err error) {
+		if nat_gateways, err = LoadNATGateways(region.Clients.EC2, nil); err != nil {
+			nat_gateways = unlinked(prev.nat_gateways).(map[string]*NATGateway)
+		}
 		return
 	}))
 
+	var failed []string
 	for _, errchan := range errs {
 		if err := <-errchan; err != nil {
-			return err
+			failed = append(failed, err.Error())
 		}
 	}
 
@@ -266,22 +461,65 @@ func (region *Region) Refresh() error {
 	prev_region := region
 
 	// create a new region to populate
-	region = NewRegion(prev_region.Name)
+	region = newRegion(prev_region.Name)
 
-	// disable the new Throttle and use the previous
-	region.Throttle.stop()
+	// reuse the clients and Throttle of the previous
+	region.Account = prev_region.Account
+	region.Clients = prev_region.Clients
 	region.Throttle = prev_region.Throttle
+	region.Poller = prev_region.Poller
 	region.Prices = prev_region.Prices
 	region.Mutex = prev_region.Mutex
 	region.SetSSHKeyPath(prev_region.sshKeyPath)
+	region.Events = prev_region.Events
+	region.MetricDefinitions = prev_region.MetricDefinitions
+	region.History = prev_region.History
+	region.Loaders = statuses
+	region.loaded = &loaded{
+		vpcs:                    vpcs,
+		security_groups:         security_groups,
+		acls:                    acls,
+		route_tables:            route_tables,
+		subnets:                 subnets,
+		elbs:                    elbs,
+		instances:               instances,
+		availability_zones:      availability_zones,
+		internet_gateways:       internet_gateways,
+		customer_gateways:       customer_gateways,
+		vp_gateways:             vp_gateways,
+		vpn_connections:         vpn_connections,
+		vpc_endpoints:           vpc_endpoints,
+		vpc_peering_connections: vpc_peering_connections,
+		as_groups:               as_groups,
+		amis:                    amis,
+		db_instances:            db_instances,
+		ec_clusters:             ec_clusters,
+		sqs_queues:              sqs_queues,
+		sns_topics:              sns_topics,
+		sns_subscribers:         sns_subscribers,
+		cloudwatch_alarms:       cloudwatch_alarms,
+		lambda_functions:        lambda_functions,
+		enis:                    enis,
+		nat_gateways:            nat_gateways,
+	}
 
 	// swap the new region data into
 
 	// thi sisso gross
 	defer func() {
-		region.Lock() // using shared mutex
-		defer region.Unlock()
+		region.Refreshed = time.Now()
+		region.RefreshDuration = region.Refreshed.Sub(refresh_start)
+		// nothing to compare against on the first refresh
+		var events []*Event
+		if region.Events != nil && !prev_region.Refreshed.IsZero() {
+			events = Diff(region.Key(), prev_region.Items, region.Items, region.Refreshed)
+		}
+		region.Lock()          // using shared mutex
 		*prev_region = *region // copy new region in
+		region.Unlock()
+		if len(events) > 0 {
+			region.Events.Publish(events...)
+		}
 	}()
 
 	for _, inst := range instances {
@@ -790,6 +1028,7 @@ func (region *Region) Refresh() error {
 	}
 	for _, sub := range sns_subscribers {
 		sub.Region = region
+		sub.AccountId = accountId
 		region.SNSSubscriptions = append(region.SNSSubscriptions, sub)
 		if topic, exists := sns_topics[sub.TopicArn]; exists {
 			topic.Subscribers = append(topic.Subscribers, sub)
@@ -1004,83 +1243,121 @@ func (region *Region) Refresh() error {
 	}
 	for _, newinst := range region.Instances {
 		if oldinst, exists := oldinsts[newinst.InstanceId]; exists {
+			delete(oldinsts, newinst.InstanceId)
+			oldinst.sysInfo_me.RLock()
 			newinst.Unreachable = oldinst.Unreachable
+			newinst.UnreachableReason = oldinst.UnreachableReason
+			newinst.HostKeyAlert = oldinst.HostKeyAlert
 			newinst.SysInfo = oldinst.SysInfo
 			newinst.Stats = oldinst.Stats
+			oldinst.sysInfo_me.RUnlock()
 		}
 	}
+	// instances that are gone won't be polled again
+	for _, oldinst := range oldinsts {
+		oldinst.sysInfo_me.Lock()
+		if oldinst.SysInfo != nil {
+			oldinst.SysInfo.Close()
+			oldinst.SysInfo = nil
+		}
+		oldinst.sysInfo_me.Unlock()
+	}
 
 	for _, v := range vpcs {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range security_groups {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range acls {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range route_tables {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range subnets {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range elbs {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range instances {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range availability_zones {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range internet_gateways {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range customer_gateways {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range vp_gateways {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range vpn_connections {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range vpc_endpoints {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range vpc_peering_connections {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range as_groups {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range amis {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range db_instances {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range ec_clusters {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range sqs_queues {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range sns_topics {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range cloudwatch_alarms {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range lambda_functions {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range enis {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 	for _, v := range nat_gateways {
-		region.Items[v.Id] = v
+		v.AccountId = accountId
+		region.Items[ItemKey(accountId, v.Id)] = v
 	}
 
 	fmt.Println("processing finished in", time.Since(start))
@@ -1089,13 +1366,20 @@ func (region *Region) Refresh() error {
 
 	var erraggregates [][][]chan error
 
-	// erraggregates = append(erraggregates, region.RefreshInstances())
-	erraggregates = append(erraggregates, region.RefreshElasticCacheClusters())
-	erraggregates = append(erraggregates, region.RefreshELBs())
-	erraggregates = append(erraggregates, region.RefreshLambdaFunctions())
-	erraggregates = append(erraggregates, region.RefreshRDS())
-	erraggregates = append(erraggregates, region.RefreshSNSTopics())
-	erraggregates = append(erraggregates, region.RefreshSQSQueues())
+	// the pollers queue their cloudwatch metrics to be fetched together
+	metrics := &MetricFetcher{}
+
+	erraggregates = append(erraggregates, region.RefreshElasticCacheClusters(metrics))
+	erraggregates = append(erraggregates, region.RefreshELBs(metrics))
+	erraggregates = append(erraggregates, region.RefreshLambdaFunctions(metrics))
+	erraggregates = append(erraggregates, region.RefreshRDS(metrics))
+	erraggregates = append(erraggregates, region.RefreshSNSTopics(metrics))
+	erraggregates = append(erraggregates, region.RefreshSQSQueues(metrics))
+	region.RefreshInstanceMetrics(metrics)
+	if err := region.PollMetricDefinitions(metrics); err != nil {
+		fmt.Println(err)
+	}
+	erraggregates = append(erraggregates, [][]chan error{metrics.Fetch(region.Clients.CloudWatch, region.Throttle)})
 
 	for _, a := range erraggregates {
 		for _, b := range a {
@@ -1107,98 +1391,116 @@ func (region *Region) Refresh() error {
 		}
 	}
 
+	region.recordHistory(prev_region.Items, time.Now())
+
 	fmt.Println("stats finished in", time.Since(start))
 	tracker.Report()
 
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d loaders failed: %s", len(failed), len(errs), strings.Join(failed, "; "))
+	}
+
 	return nil
 
 }
 
-func (region *Region) RefreshLambdaFunctions() [][]chan error {
+func (region *Region) RefreshLambdaFunctions(metrics *MetricFetcher) [][]chan error {
 
 	var errs [][]chan error
 
 	for _, lf := range region.LambdaFunctions {
-		errs = append(errs, lf.Poll())
+		errs = append(errs, lf.Poll(metrics))
 	}
 
 	return errs
 
 }
 
-func (region *Region) RefreshSNSTopics() [][]chan error {
+func (region *Region) RefreshSNSTopics(metrics *MetricFetcher) [][]chan error {
 
 	var errs [][]chan error
 
 	for _, t := range region.SNSTopics {
-		errs = append(errs, t.Poll())
+		errs = append(errs, t.Poll(metrics))
 	}
 
 	return errs
 
 }
 
-func (region *Region) RefreshSQSQueues() [][]chan error {
+func (region *Region) RefreshSQSQueues(metrics *MetricFetcher) [][]chan error {
 
 	var errs [][]chan error
 
 	for _, q := range region.SQSQueues {
-		errs = append(errs, q.Poll())
+		errs = append(errs, q.Poll(metrics))
 	}
 
 	return errs
 
 }
 
-func (region *Region) RefreshElasticCacheClusters() [][]chan error {
+func (region *Region) RefreshElasticCacheClusters(metrics *MetricFetcher) [][]chan error {
 
 	var errs [][]chan error
 
 	for _, ecc := range region.ElasticCacheClusters {
-		errs = append(errs, ecc.Poll())
+		errs = append(errs, ecc.Poll(metrics))
 	}
 
 	return errs
 
 }
 
-func (region *Region) RefreshRDS() [][]chan error {
+func (region *Region) RefreshRDS(metrics *MetricFetcher) [][]chan error {
 
 	var errs [][]chan error
 
 	for _, db := range region.DBInstances {
-		errs = append(errs, db.Poll())
+		errs = append(errs, db.Poll(metrics))
 	}
 
 	return errs
 
 }
 
-func (region *Region) RefreshELBs() [][]chan error {
+func (region *Region) RefreshELBs(metrics *MetricFetcher) [][]chan error {
 
 	var errs [][]chan error
 
 	for _, elb := range region.ELBs {
-		errs = append(errs, elb.Poll())
+		errs = append(errs, elb.Poll(metrics))
 	}
 
 	return errs
 
 }
 
-func (region *Region) RefreshInstances() [][]chan error {
+// RefreshInstanceMetrics queues cloudwatch stats for the instances
+// that aren't polled over ssh
+func (region *Region) RefreshInstanceMetrics(metrics *MetricFetcher) {
+	for _, inst := range region.Instances {
+		inst.PollMetrics(metrics)
+	}
+}
 
-	return nil
+// RefreshInstances polls all the instances at once, limited only by the
+// Poller's ssh sessions.  Fleet.Run spreads polls out with Poller.Run.
+func (region *Region) RefreshInstances() [][]chan error {
 
-	var errs [][]chan error
+	var errs []chan error
 
 	for _, inst := range region.Instances {
-		errs = append(errs, inst.Poll())
+		errc := make(chan error, 1)
+		go func(inst *Instance) {
+			errc <- region.Poller.Poll(inst)
+		}(inst)
+		errs = append(errs, errc)
 	}
 
 	fmt.Println("polling", len(errs), "instances")
 
-	return errs
+	return [][]chan error{errs}
 
 }
 
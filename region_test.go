@@ -0,0 +1,398 @@
+package window
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elasticache"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+const testAccountId = "123456789012"
+
+func newFakeClients() *Clients {
+	return &Clients{
+		EC2: &fakeEC2{
+			AvailabilityZones: []*ec2.AvailabilityZone{
+				{ZoneName: aws.String("us-test-1a"), RegionName: aws.String("us-test-1"), State: aws.String("available")},
+				{ZoneName: aws.String("us-test-1b"), RegionName: aws.String("us-test-1"), State: aws.String("available")},
+			},
+			Vpcs: []*ec2.Vpc{
+				{VpcId: aws.String("vpc-1"), CidrBlock: aws.String("10.0.0.0/16"), State: aws.String("available"),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("main")}}},
+			},
+			Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-1"), VpcId: aws.String("vpc-1"), CidrBlock: aws.String("10.0.1.0/24"), AvailabilityZone: aws.String("us-test-1a")},
+			},
+			RouteTables: []*ec2.RouteTable{
+				{
+					RouteTableId: aws.String("rtb-1"),
+					VpcId:        aws.String("vpc-1"),
+					Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-1")}},
+					Routes: []*ec2.Route{
+						{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1")},
+					},
+				},
+			},
+			InternetGateways: []*ec2.InternetGateway{
+				{InternetGatewayId: aws.String("igw-1"), Attachments: []*ec2.InternetGatewayAttachment{{VpcId: aws.String("vpc-1")}}},
+			},
+			SecurityGroups: []*ec2.SecurityGroup{
+				{GroupId: aws.String("sg-1"), GroupName: aws.String("web"), VpcId: aws.String("vpc-1")},
+				{GroupId: aws.String("sg-2"), GroupName: aws.String("db"), VpcId: aws.String("vpc-1")},
+			},
+			Images: []*ec2.Image{
+				{ImageId: aws.String("ami-1"), Name: aws.String("ubuntu")},
+			},
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{
+					{
+						InstanceId:      aws.String("i-1"),
+						ImageId:         aws.String("ami-1"),
+						InstanceType:    aws.String("t2.micro"),
+						VpcId:           aws.String("vpc-1"),
+						SubnetId:        aws.String("subnet-1"),
+						SourceDestCheck: aws.Bool(true),
+						Placement:       &ec2.Placement{AvailabilityZone: aws.String("us-test-1a")},
+						State:           &ec2.InstanceState{Name: aws.String("running")},
+						SecurityGroups:  []*ec2.GroupIdentifier{{GroupId: aws.String("sg-1"), GroupName: aws.String("web")}},
+						Tags:            []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("web-1")}},
+					},
+					{
+						InstanceId:   aws.String("i-2"),
+						ImageId:      aws.String("ami-1"),
+						InstanceType: aws.String("m1.small"),
+						Placement:    &ec2.Placement{AvailabilityZone: aws.String("us-test-1b")},
+						State:        &ec2.InstanceState{Name: aws.String("stopped")},
+					},
+				},
+			}},
+			NetworkInterfaces: []*ec2.NetworkInterface{
+				{
+					NetworkInterfaceId: aws.String("eni-1"),
+					VpcId:              aws.String("vpc-1"),
+					SubnetId:           aws.String("subnet-1"),
+					AvailabilityZone:   aws.String("us-test-1a"),
+					Attachment:         &ec2.NetworkInterfaceAttachment{InstanceId: aws.String("i-1")},
+				},
+			},
+		},
+		ELB: &fakeELB{
+			LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+				{
+					LoadBalancerName:  aws.String("web-elb"),
+					VPCId:             aws.String("vpc-1"),
+					AvailabilityZones: []*string{aws.String("us-test-1a")},
+					Subnets:           []*string{aws.String("subnet-1")},
+					SecurityGroups:    []*string{aws.String("web")},
+					Instances:         []*elb.Instance{{InstanceId: aws.String("i-1")}},
+				},
+			},
+		},
+		AutoScaling: &fakeAutoScaling{
+			AutoScalingGroups: []*autoscaling.Group{
+				{
+					AutoScalingGroupName: aws.String("web-asg"),
+					AutoScalingGroupARN:  aws.String("arn:aws:autoscaling:us-test-1:123456789012:autoScalingGroup:web-asg"),
+					Instances:            []*autoscaling.Instance{{InstanceId: aws.String("i-1")}},
+				},
+			},
+		},
+		ElastiCache: &fakeElastiCache{
+			CacheClusters: []*elasticache.CacheCluster{
+				{
+					CacheClusterId: aws.String("cache-1"),
+					Engine:         aws.String("redis"),
+					SecurityGroups: []*elasticache.SecurityGroupMembership{{SecurityGroupId: aws.String("sg-2")}},
+					CacheNodes:     []*elasticache.CacheNode{{CacheNodeId: aws.String("0001"), CustomerAvailabilityZone: aws.String("us-test-1b")}},
+				},
+			},
+		},
+		RDS: &fakeRDS{
+			DBInstances: []*rds.DBInstance{
+				{
+					DBInstanceIdentifier: aws.String("db-1"),
+					DBInstanceClass:      aws.String("db.t2.micro"),
+					Engine:               aws.String("postgres"),
+					AvailabilityZone:     aws.String("us-test-1b"),
+					DBSubnetGroup:        &rds.DBSubnetGroup{VpcId: aws.String("vpc-1")},
+					VpcSecurityGroups:    []*rds.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-2")}},
+				},
+			},
+		},
+		CloudWatch: &fakeCloudWatch{
+			MetricAlarms: []*cloudwatch.MetricAlarm{
+				{
+					AlarmName:    aws.String("web-1-cpu"),
+					AlarmArn:     aws.String("arn:aws:cloudwatch:us-test-1:123456789012:alarm:web-1-cpu"),
+					StateValue:   aws.String("ALARM"),
+					Dimensions:   []*cloudwatch.Dimension{{Name: aws.String("InstanceId"), Value: aws.String("i-1")}},
+					AlarmActions: []*string{aws.String("arn:aws:sns:us-test-1:123456789012:alerts")},
+				},
+			},
+		},
+		SQS: &fakeSQS{},
+		SNS: &fakeSNS{
+			Topics: map[string]map[string]*string{
+				"arn:aws:sns:us-test-1:123456789012:alerts": {
+					"TopicArn": aws.String("arn:aws:sns:us-test-1:123456789012:alerts"),
+				},
+			},
+			Subscriptions: []*sns.Subscription{
+				{
+					SubscriptionArn: aws.String("arn:aws:sns:us-test-1:123456789012:alerts:1"),
+					TopicArn:        aws.String("arn:aws:sns:us-test-1:123456789012:alerts"),
+					Endpoint:        aws.String("ops@example.com"),
+					Protocol:        aws.String("email"),
+				},
+			},
+		},
+		Lambda: &fakeLambda{
+			Functions: []*lambda.FunctionConfiguration{
+				{
+					FunctionName: aws.String("resize"),
+					FunctionArn:  aws.String("arn:aws:lambda:us-test-1:123456789012:function:resize"),
+					VpcConfig: &lambda.VpcConfigResponse{
+						VpcId:            aws.String("vpc-1"),
+						SubnetIds:        []*string{aws.String("subnet-1")},
+						SecurityGroupIds: []*string{aws.String("sg-1")},
+					},
+				},
+			},
+		},
+		STS: &fakeSTS{Account: aws.String(testAccountId)},
+	}
+}
+
+func newFakeRegion() *Region {
+	region := newRegion("us-test-1")
+	region.Account = &Account{}
+	region.Clients = newFakeClients()
+	region.Throttle = NewThrottle(10, 1000, time.Second)
+	return region
+}
+
+func TestRegionRefreshLinking(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	poller := NewInstancePoller(1)
+	region.Poller = poller
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if region.Poller != poller {
+		t.Error("Expected the poller carried over")
+	}
+
+	if region.Account.Id != testAccountId {
+		t.Errorf("Expected account id %q, got %q", testAccountId, region.Account.Id)
+	}
+
+	if len(region.VPCs) != 1 {
+		t.Fatalf("Expected 1 vpc, got %d", len(region.VPCs))
+	}
+	vpc := region.VPCs[0]
+	if vpc.Name != "main" || vpc.Region == nil || vpc.Region.Name != region.Name {
+		t.Errorf("vpc not linked to region: %+v", vpc)
+	}
+
+	if len(region.Instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(region.Instances))
+	}
+	var web, classic *Instance
+	for _, inst := range region.Instances {
+		switch inst.InstanceId {
+		case "i-1":
+			web = inst
+		case "i-2":
+			classic = inst
+		}
+	}
+
+	if web.VPC != vpc || !InstanceInSlice(vpc.Instances, web) {
+		t.Error("instance not linked to vpc")
+	}
+	if web.Subnet == nil || web.Subnet.SubnetId != "subnet-1" || !InstanceInSlice(web.Subnet.Instances, web) {
+		t.Error("instance not linked to subnet")
+	}
+	if web.AvailabilityZone == nil || web.AvailabilityZone.Name != "us-test-1a" {
+		t.Error("instance not linked to availability zone")
+	}
+	if web.AMI == nil || web.AMI.ImageId != "ami-1" || len(web.AMI.Instances) != 2 {
+		t.Error("instance not linked to ami")
+	}
+	if len(web.SecurityGroups) != 1 || web.SecurityGroups[0].GroupId != "sg-1" {
+		t.Error("instance not linked to security group")
+	}
+	if len(web.ENIs) != 1 || web.ENIs[0].NetworkInterfaceId != "eni-1" {
+		t.Error("instance not linked to eni")
+	}
+	if web.ELB == nil || web.ELB.Name != "web-elb" || !InstanceInSlice(web.ELB.Instances, web) {
+		t.Error("instance not linked to elb")
+	}
+	if web.AutoScalingGroup == nil || web.AutoScalingGroup.Name != "web-asg" {
+		t.Error("instance not linked to autoscaling group")
+	}
+	if !AutoScalingGroupInSlice(web.ELB.AutoScalingGroups, web.AutoScalingGroup) || !AutoScalingGroupInSlice(vpc.AutoScalingGroups, web.AutoScalingGroup) {
+		t.Error("autoscaling group not linked to elb and vpc")
+	}
+	if len(web.CloudWatchAlarms) != 1 || len(web.CloudWatchAlarms[0].AlarmActionSNSs) != 1 {
+		t.Error("instance not linked to alarm and alarm not linked to topic")
+	}
+
+	if classic.VPC != nil || classic.Classic != region.Classic || !InstanceInSlice(region.Classic.Instances, classic) {
+		t.Error("instance without vpc not linked to classic")
+	}
+
+	subnet := web.Subnet
+	if subnet.VPC != vpc || subnet.InternetGateway == nil || subnet.InternetGateway.InternetGatewayId != "igw-1" {
+		t.Error("subnet not linked to vpc and internet gateway")
+	}
+	if vpc.InternetGateway != subnet.InternetGateway {
+		t.Error("internet gateway not linked to vpc")
+	}
+	if len(vpc.RouteTables) != 1 || len(subnet.RouteTables) != 1 {
+		t.Error("route table not linked")
+	}
+
+	if len(region.DBInstances) != 1 {
+		t.Fatalf("Expected 1 db instance, got %d", len(region.DBInstances))
+	}
+	if db := region.DBInstances[0]; db.VPC != vpc || db.AvailabilityZone == nil || db.AvailabilityZone.Name != "us-test-1b" {
+		t.Error("db instance not linked to vpc and availability zone")
+	}
+
+	if len(region.ElasticCacheClusters) != 1 {
+		t.Fatalf("Expected 1 cache cluster, got %d", len(region.ElasticCacheClusters))
+	}
+	if ecc := region.ElasticCacheClusters[0]; ecc.VPC != vpc || len(ecc.SecurityGroups) != 1 || len(ecc.AvailabilityZones) != 1 {
+		t.Error("cache cluster not linked to vpc, security group and availability zone")
+	}
+
+	if len(region.LambdaFunctions) != 1 {
+		t.Fatalf("Expected 1 lambda function, got %d", len(region.LambdaFunctions))
+	}
+	if lf := region.LambdaFunctions[0]; lf.VPC != vpc || len(lf.Subnets) != 1 || len(lf.SecurityGroups) != 1 {
+		t.Error("lambda function not linked to vpc, subnet and security group")
+	}
+
+	if len(region.SNSTopics) != 1 || len(region.SNSTopics[0].Subscribers) != 1 {
+		t.Error("sns subscription not linked to topic")
+	}
+
+	if v, exists := region.Items[ItemKey(testAccountId, "inst:i-1")]; !exists || v != web {
+		t.Error("instance not in items under namespaced key")
+	}
+	if web.AccountId != testAccountId || vpc.AccountId != testAccountId {
+		t.Error("account id not set on resources")
+	}
+
+}
+
+func TestRegionRefreshCarriesInstanceState(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	si := sysinfo.NewSystemInfoCollector("", nil, 2)
+	for _, inst := range region.Instances {
+		inst.Unreachable = inst.InstanceId == "i-2"
+		if inst.InstanceId == "i-1" {
+			inst.SysInfo = si
+		}
+	}
+	// the collectors of instances gone from the region are closed
+	gone := runningInstance("i-gone")
+	gone.SysInfo = sysinfo.NewSystemInfoCollector("", nil, 2)
+	region.Instances = append(region.Instances, gone)
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range region.Instances {
+		if inst.Unreachable != (inst.InstanceId == "i-2") {
+			t.Errorf("%s: instance state not carried across refresh", inst.InstanceId)
+		}
+		if inst.InstanceId == "i-1" && inst.SysInfo != si {
+			t.Error("Expected the collector carried across refresh")
+		}
+	}
+	if gone.SysInfo != nil {
+		t.Error("Expected the collector of a terminated instance closed")
+	}
+
+}
+
+type failingVPCs struct{ *fakeEC2 }
+
+func (f failingVPCs) DescribeVpcs(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	return nil, errors.New("UnauthorizedOperation")
+}
+
+func TestRegionRefreshKeepsFailedLoaderResults(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	old_vpc := region.VPCs[0]
+
+	region.Clients.EC2 = failingVPCs{region.Clients.EC2.(*fakeEC2)}
+
+	if err := region.Refresh(); err == nil || !strings.Contains(err.Error(), "LoadVPCs") {
+		t.Errorf("Expected LoadVPCs error, got %v", err)
+	}
+
+	if len(region.VPCs) != 1 {
+		t.Fatalf("Expected previous vpc to be kept, got %d vpcs", len(region.VPCs))
+	}
+	vpc := region.VPCs[0]
+	if vpc == old_vpc {
+		t.Error("previous vpc reused rather than copied")
+	}
+	if len(old_vpc.Instances) != 1 {
+		t.Error("previous vpc modified by refresh")
+	}
+	if len(vpc.Instances) != 1 || vpc.Instances[0].VPC != vpc || vpc.Instances[0] == old_vpc.Instances[0] {
+		t.Error("kept vpc not relinked to new instances")
+	}
+	if len(vpc.Subnets) != 1 || vpc.Subnets[0].VPC != vpc {
+		t.Error("kept vpc not relinked to subnets")
+	}
+
+	status := region.Loaders["LoadVPCs"]
+	if status == nil || !status.Failing() || status.LastSuccess.IsZero() || !strings.Contains(status.LastError, "UnauthorizedOperation") {
+		t.Errorf("unexpected LoadVPCs status: %+v", status)
+	}
+	if failing := region.FailingLoaders(); len(failing) != 1 || failing[0].Name != "LoadVPCs" {
+		t.Errorf("Expected only LoadVPCs failing, got %v", failing)
+	}
+
+	region.Clients.EC2 = region.Clients.EC2.(failingVPCs).fakeEC2
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if status := region.Loaders["LoadVPCs"]; status.Failing() {
+		t.Errorf("LoadVPCs still failing after recovery: %+v", status)
+	}
+
+}
@@ -25,9 +25,10 @@ type (
 		// The ID of the VPC.
 		VpcId string
 
-		Name  string
-		Id    string
-		State string
+		Name      string
+		Id        string
+		AccountId string
+		State     string
 	}
 
 	RouteTableByNameAsc []*RouteTable
@@ -39,9 +40,9 @@ func (a RouteTableByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadRouteTables(input *ec2.DescribeRouteTablesInput) (map[string]*RouteTable, error) {
+func LoadRouteTables(client EC2API, input *ec2.DescribeRouteTablesInput) (map[string]*RouteTable, error) {
 
-	resp, err := EC2Client.DescribeRouteTables(input)
+	resp, err := client.DescribeRouteTables(input)
 	if err != nil {
 		return nil, err
 	}
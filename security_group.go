@@ -35,6 +35,7 @@ type (
 
 		Name                 string
 		Id                   string
+		AccountId            string
 		State                string
 		Instances            []*Instance
 		ELBs                 []*ELB
@@ -66,9 +67,9 @@ func (a SecurityGroupIpPermissionsByPortAsc) Less(i, j int) bool {
 	return true
 }
 
-func LoadSecurityGroups(input *ec2.DescribeSecurityGroupsInput) (map[string]*SecurityGroup, error) {
+func LoadSecurityGroups(client EC2API, input *ec2.DescribeSecurityGroupsInput) (map[string]*SecurityGroup, error) {
 
-	resp, err := EC2Client.DescribeSecurityGroups(input)
+	resp, err := client.DescribeSecurityGroups(input)
 	if err != nil {
 		return nil, err
 	}
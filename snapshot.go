@@ -0,0 +1,434 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A snapshot is the json encoding of a refreshed Region.  Links between
+// resources are encoded as the linked resource's Items key, resources
+// that are not in Items (the vpc local availability zones) are inlined,
+// and *Region fields and fields tagged json:"-" are dropped.  Of the
+// region's Account only its name and id are kept, as snapshots are
+// exported and its credential config must not leave the process.
+// DecodeSnapshot rebuilds the pointer graph from the keys.
+
+type (
+	snapshot struct {
+		Time    time.Time                  `json:"time"`
+		Region  map[string]json.RawMessage `json:"region"`
+		Classic map[string]json.RawMessage `json:"classic"`
+		Items   map[string]*snapshotItem   `json:"items"`
+	}
+
+	snapshotItem struct {
+		Kind   string                     `json:"kind"`
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+
+	snapshotEncoder struct {
+		region *Region
+		keys   map[interface{}]string
+		seen   map[interface{}]bool
+	}
+
+	snapshotAccount struct {
+		Name string `json:"name"`
+		Id   string `json:"id"`
+	}
+
+	snapshotDecoder struct {
+		region *Region
+		items  map[string]reflect.Value
+	}
+)
+
+// classicRef is the key used for links to the region's Classic
+const classicRef = "$classic"
+
+var (
+	regionType = reflect.TypeOf(&Region{})
+	kindTypes  = map[string]reflect.Type{}
+)
+
+func init() {
+	for t := range linkTypes {
+		kindTypes[t.Elem().Name()] = t.Elem()
+	}
+}
+
+// EncodeSnapshot encodes the region.  The caller must hold the region's lock.
+func EncodeSnapshot(region *Region) ([]byte, error) {
+
+	enc := newSnapshotEncoder(region)
+
+	snap := &snapshot{
+		Time:  region.Refreshed,
+		Items: map[string]*snapshotItem{},
+	}
+
+	var err error
+	if snap.Region, err = enc.fields(reflect.ValueOf(region).Elem()); err != nil {
+		return nil, err
+	}
+	if region.Account != nil {
+		if snap.Region["Account"], err = json.Marshal(&snapshotAccount{
+			Name: region.Account.Name,
+			Id:   region.Account.Id,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if snap.Classic, err = enc.fields(reflect.ValueOf(region.Classic).Elem()); err != nil {
+		return nil, err
+	}
+	for key, v := range region.Items {
+		fields, err := enc.fields(reflect.ValueOf(v).Elem())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		snap.Items[key] = &snapshotItem{Kind: resourceKind(v), Fields: fields}
+	}
+
+	return json.Marshal(snap)
+
+}
+
+func newSnapshotEncoder(region *Region) *snapshotEncoder {
+	enc := &snapshotEncoder{
+		region: region,
+		keys:   map[interface{}]string{},
+		seen:   map[interface{}]bool{},
+	}
+	for key, v := range region.Items {
+		enc.keys[v] = key
+	}
+	return enc
+}
+
+func (enc *snapshotEncoder) fields(v reflect.Value) (map[string]json.RawMessage, error) {
+
+	fields := map[string]json.RawMessage{}
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if len(f.PkgPath) > 0 || f.Tag.Get("json") == "-" || f.Type == regionType {
+			continue
+		}
+		var (
+			data []byte
+			err  error
+		)
+		switch t := f.Type; {
+		case linkTypes[t]:
+			data, err = enc.link(v.Field(i))
+		case t.Kind() == reflect.Slice && linkTypes[t.Elem()]:
+			links := make([]json.RawMessage, v.Field(i).Len())
+			for j := range links {
+				if links[j], err = enc.link(v.Field(i).Index(j)); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				data, err = json.Marshal(links)
+			}
+		case hasLinks(t):
+			data, err = enc.nested(v.Field(i))
+		default:
+			data, err = json.Marshal(v.Field(i).Interface())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		fields[f.Name] = data
+	}
+
+	return fields, nil
+
+}
+
+func (enc *snapshotEncoder) link(v reflect.Value) (json.RawMessage, error) {
+	if v.IsNil() {
+		return json.RawMessage("null"), nil
+	}
+	ptr := v.Interface()
+	if ptr == interface{}(enc.region.Classic) {
+		return json.Marshal(classicRef)
+	}
+	if key, exists := enc.keys[ptr]; exists {
+		return json.Marshal(key)
+	}
+	// not an item, inline it once
+	if enc.seen[ptr] {
+		return json.RawMessage("null"), nil
+	}
+	enc.seen[ptr] = true
+	defer delete(enc.seen, ptr)
+	fields, err := enc.fields(v.Elem())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// nested encodes a value that is not a resource but holds links to them
+func (enc *snapshotEncoder) nested(v reflect.Value) (json.RawMessage, error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return enc.nested(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		elems := make([]json.RawMessage, v.Len())
+		for i := range elems {
+			var err error
+			if elems[i], err = enc.nested(v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(elems)
+	}
+	fields, err := enc.fields(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// DecodeSnapshot decodes a region encoded by EncodeSnapshot.
+// The region has no clients and is intended to be read only.
+func DecodeSnapshot(data []byte) (*Region, error) {
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+
+	region := newRegion("")
+	dec := &snapshotDecoder{
+		region: region,
+		items:  map[string]reflect.Value{},
+	}
+
+	// create every item before linking them
+	for key, item := range snap.Items {
+		t, exists := kindTypes[item.Kind]
+		if !exists {
+			return nil, fmt.Errorf("%s: unknown kind %q", key, item.Kind)
+		}
+		dec.items[key] = reflect.New(t)
+	}
+
+	if data, exists := snap.Region["Account"]; exists {
+		var account *snapshotAccount
+		if err := json.Unmarshal(data, &account); err != nil {
+			return nil, fmt.Errorf("Account: %v", err)
+		}
+		if account != nil {
+			region.Account = &Account{Name: account.Name, Id: account.Id}
+		}
+		delete(snap.Region, "Account")
+	}
+	if err := dec.fields(reflect.ValueOf(region).Elem(), snap.Region); err != nil {
+		return nil, err
+	}
+	if err := dec.fields(reflect.ValueOf(region.Classic).Elem(), snap.Classic); err != nil {
+		return nil, err
+	}
+	region.Classic.Region = region
+
+	for key, item := range snap.Items {
+		v := dec.items[key]
+		if err := dec.fields(v.Elem(), item.Fields); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		region.Items[key] = v.Interface()
+	}
+
+	region.Refreshed = snap.Time
+
+	return region, nil
+
+}
+
+func (dec *snapshotDecoder) fields(v reflect.Value, fields map[string]json.RawMessage) error {
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if len(f.PkgPath) > 0 || f.Tag.Get("json") == "-" {
+			continue
+		}
+		if f.Type == regionType {
+			v.Field(i).Set(reflect.ValueOf(dec.region))
+			continue
+		}
+		data, exists := fields[f.Name]
+		if !exists {
+			continue
+		}
+		switch t := f.Type; {
+		case linkTypes[t]:
+			link, err := dec.link(t, data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", f.Name, err)
+			}
+			v.Field(i).Set(link)
+		case t.Kind() == reflect.Slice && linkTypes[t.Elem()]:
+			var raws []json.RawMessage
+			if err := json.Unmarshal(data, &raws); err != nil {
+				return fmt.Errorf("%s: %v", f.Name, err)
+			}
+			if raws == nil {
+				continue
+			}
+			links := reflect.MakeSlice(t, 0, len(raws))
+			for _, raw := range raws {
+				link, err := dec.link(t.Elem(), raw)
+				if err != nil {
+					return fmt.Errorf("%s: %v", f.Name, err)
+				}
+				if !link.IsNil() {
+					links = reflect.Append(links, link)
+				}
+			}
+			v.Field(i).Set(links)
+		case hasLinks(t):
+			nested, err := dec.nested(t, data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", f.Name, err)
+			}
+			v.Field(i).Set(nested)
+		default:
+			if err := json.Unmarshal(data, v.Field(i).Addr().Interface()); err != nil {
+				return fmt.Errorf("%s: %v", f.Name, err)
+			}
+		}
+	}
+
+	if vpc, ok := v.Addr().Interface().(*VPC); ok {
+		vpc.azs = map[*AvailabilityZone]*AvailabilityZone{}
+	}
+
+	return nil
+
+}
+
+func (dec *snapshotDecoder) link(t reflect.Type, data json.RawMessage) (reflect.Value, error) {
+
+	switch s := strings.TrimSpace(string(data)); {
+	case s == "null":
+		return reflect.Zero(t), nil
+	case strings.HasPrefix(s, `"`):
+		var key string
+		if err := json.Unmarshal(data, &key); err != nil {
+			return reflect.Value{}, err
+		}
+		if key == classicRef {
+			return reflect.ValueOf(dec.region.Classic), nil
+		}
+		if v, exists := dec.items[key]; exists && v.Type() == t {
+			return v, nil
+		}
+		// dangling links are dropped
+		return reflect.Zero(t), nil
+	default:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t.Elem())
+		if err := dec.fields(v.Elem(), fields); err != nil {
+			return reflect.Value{}, err
+		}
+		return v, nil
+	}
+
+}
+
+func (dec *snapshotDecoder) nested(t reflect.Type, data json.RawMessage) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		if strings.TrimSpace(string(data)) == "null" {
+			return reflect.Zero(t), nil
+		}
+		elem, err := dec.nested(t.Elem(), data)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t.Elem())
+		v.Elem().Set(elem)
+		return v, nil
+	case reflect.Slice:
+		var raws []json.RawMessage
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return reflect.Value{}, err
+		}
+		if raws == nil {
+			return reflect.Zero(t), nil
+		}
+		v := reflect.MakeSlice(t, len(raws), len(raws))
+		for i, raw := range raws {
+			elem, err := dec.nested(t.Elem(), raw)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return reflect.Value{}, err
+	}
+	v := reflect.New(t).Elem()
+	if err := dec.fields(v, fields); err != nil {
+		return reflect.Value{}, err
+	}
+	return v, nil
+}
+
+var (
+	has_links    = map[reflect.Type]bool{}
+	has_links_me sync.Mutex
+)
+
+// hasLinks reports whether values of a type that is not itself a link
+// hold links to resources, such as ECCNodeStats.Cluster
+func hasLinks(t reflect.Type) bool {
+	has_links_me.Lock()
+	defer has_links_me.Unlock()
+	return has_links_locked(t)
+}
+
+func has_links_locked(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		if linkTypes[t] || (t.Kind() == reflect.Slice && linkTypes[t.Elem()]) {
+			return true
+		}
+		return has_links_locked(t.Elem())
+	case reflect.Struct:
+	default:
+		return false
+	}
+	if found, exists := has_links[t]; exists {
+		return found
+	}
+	has_links[t] = false // guards recursive types
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) == 0 && f.Tag.Get("json") != "-" && f.Type != regionType && has_links_locked(f.Type) {
+			has_links[t] = true
+			break
+		}
+	}
+	return has_links[t]
+}
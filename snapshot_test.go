@@ -0,0 +1,110 @@
+package window
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	if err := region.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	region.Account.Name = "prod"
+	region.Account.Profile = "prod-profile"
+	region.Account.RoleARN = "arn:aws:iam::123456789012:role/window"
+	region.Account.ExternalID = "s3cr3t-external-id"
+	region.Account.Endpoint = "http://127.0.0.1:4566"
+
+	data, err := EncodeSnapshot(region)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the account's name and id are shared
+	for _, secret := range []string{region.Account.Profile, region.Account.RoleARN, region.Account.ExternalID, region.Account.Endpoint} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("Expected %q left out of the snapshot", secret)
+		}
+	}
+
+	decoded, err := DecodeSnapshot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Account == nil || decoded.Account.Name != "prod" || decoded.Account.Id != testAccountId {
+		t.Errorf("Expected account prod %s, got %+v", testAccountId, decoded.Account)
+	}
+
+	if decoded.Name != region.Name || !decoded.Refreshed.Equal(region.Refreshed) {
+		t.Errorf("Expected %s at %s, got %s at %s", region.Name, region.Refreshed, decoded.Name, decoded.Refreshed)
+	}
+	if len(decoded.Items) != len(region.Items) {
+		t.Errorf("Expected %d items, got %d", len(region.Items), len(decoded.Items))
+	}
+	if len(decoded.Instances) != 2 || len(decoded.VPCs) != 1 || len(decoded.SecurityGroups) != 2 {
+		t.Fatalf("region lists not decoded: %d instances, %d vpcs, %d security groups", len(decoded.Instances), len(decoded.VPCs), len(decoded.SecurityGroups))
+	}
+
+	vpc := decoded.VPCs[0]
+	if vpc.Region != decoded || vpc.Name != "main" || vpc.CIDR == nil || vpc.CIDR.String() != "10.0.0.0/16" {
+		t.Errorf("vpc not decoded: %+v", vpc)
+	}
+
+	web, exists := decoded.Items[ItemKey(testAccountId, "inst:i-1")].(*Instance)
+	if !exists {
+		t.Fatal("instance not in items")
+	}
+	if web.VPC != vpc || !InstanceInSlice(vpc.Instances, web) || !InstanceInSlice(decoded.Instances, web) {
+		t.Error("instance not relinked to vpc")
+	}
+	if web.Subnet == nil || web.Subnet.VPC != vpc || !InstanceInSlice(web.Subnet.Instances, web) {
+		t.Error("instance not relinked to subnet")
+	}
+	if web.ELB == nil || !InstanceInSlice(web.ELB.Instances, web) || web.ELB.Region != decoded {
+		t.Error("instance not relinked to elb")
+	}
+	if web.AMI == nil || len(web.AMI.Instances) != 2 {
+		t.Error("instance not relinked to ami")
+	}
+	if len(web.CloudWatchAlarms) != 1 || len(web.CloudWatchAlarms[0].AlarmActionSNSs) != 1 {
+		t.Error("alarm not relinked")
+	}
+
+	// vpc availability zones are not items and are inlined
+	if len(vpc.AvailabilityZones) != len(region.VPCs[0].AvailabilityZones) || len(vpc.AvailabilityZones) == 0 {
+		t.Fatalf("Expected %d vpc availability zones, got %d", len(region.VPCs[0].AvailabilityZones), len(vpc.AvailabilityZones))
+	}
+	for _, az := range vpc.AvailabilityZones {
+		if decoded.Items[ItemKey(testAccountId, az.Id)] == az {
+			t.Error("vpc availability zone decoded as region availability zone")
+		}
+		if len(az.Subnets) == 0 || az.Subnets[0] != web.Subnet {
+			t.Error("vpc availability zone not relinked to subnet")
+		}
+	}
+
+	if ecc := decoded.ElasticCacheClusters[0]; len(ecc.Stats) != 1 || ecc.Stats[0].Cluster != ecc {
+		t.Error("cache cluster stats not relinked")
+	}
+
+	var classic *Instance
+	for _, inst := range decoded.Instances {
+		if inst.InstanceId == "i-2" {
+			classic = inst
+		}
+	}
+	if classic == nil || classic.Classic != decoded.Classic || !InstanceInSlice(decoded.Classic.Instances, classic) || decoded.Classic.Region != decoded {
+		t.Error("classic not relinked")
+	}
+
+	if status, exists := decoded.Loaders["LoadVPCs"]; !exists || status.LastSuccess.IsZero() {
+		t.Error("loader status not decoded")
+	}
+
+}
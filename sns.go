@@ -24,6 +24,7 @@ type (
 
 		Name                      string
 		Id                        string
+		AccountId                 string
 		State                     string
 		TopicName                 string // tail of arn
 		Region                    *Region
@@ -31,6 +32,7 @@ type (
 		EffectiveDeliveryPolicies SNSDeliveryPolicies
 		Subscribers               []*SNSSubscription
 		Stats                     *TopicStats
+		Metrics                   map[string]*Series
 		CloudWatchAlarms          []*CloudWatchAlarm
 	}
 
@@ -50,8 +52,9 @@ type (
 		// The ARN of the subscription's topic.
 		TopicArn string
 
-		Name   string
-		Region *Region
+		Name      string
+		AccountId string
+		Region    *Region
 	}
 
 	SNSPolicy struct {
@@ -101,11 +104,11 @@ func (a SNSSubscriptionByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadSNSTopics(input *sns.ListTopicsInput) (map[string]*SNSTopic, error) {
+func LoadSNSTopics(client SNSAPI, input *sns.ListTopicsInput) (map[string]*SNSTopic, error) {
 
 	snsts := map[string]*SNSTopic{}
 
-	if err := SNSClient.ListTopicsPages(input, func(p *sns.ListTopicsOutput, _ bool) bool {
+	if err := client.ListTopicsPages(input, func(p *sns.ListTopicsOutput, _ bool) bool {
 		for _, topic := range p.Topics {
 			snsts[*topic.TopicArn] = &SNSTopic{TopicArn: *topic.TopicArn}
 		}
@@ -118,7 +121,7 @@ func LoadSNSTopics(input *sns.ListTopicsInput) (map[string]*SNSTopic, error) {
 
 	for arn, snst := range snsts {
 		go func(arn string, snst *SNSTopic) {
-			resp, err := SNSClient.GetTopicAttributes(&sns.GetTopicAttributesInput{TopicArn: aws.String(arn)})
+			resp, err := client.GetTopicAttributes(&sns.GetTopicAttributesInput{TopicArn: aws.String(arn)})
 			if err == nil {
 				snst.DeliveryPolicy = aws.StringValue(resp.Attributes["DeliveryPolicy"])
 				snst.DisplayName = aws.StringValue(resp.Attributes["DisplayName"])
@@ -158,11 +161,11 @@ func LoadSNSTopics(input *sns.ListTopicsInput) (map[string]*SNSTopic, error) {
 
 }
 
-func LoadSNSSubscriptions(input *sns.ListSubscriptionsInput) (map[string]*SNSSubscription, error) {
+func LoadSNSSubscriptions(client SNSAPI, input *sns.ListSubscriptionsInput) (map[string]*SNSSubscription, error) {
 
 	subs := map[string]*SNSSubscription{}
 
-	if err := SNSClient.ListSubscriptionsPages(input, func(p *sns.ListSubscriptionsOutput, _ bool) bool {
+	if err := client.ListSubscriptionsPages(input, func(p *sns.ListSubscriptionsOutput, _ bool) bool {
 		for _, sub := range p.Subscriptions {
 			ss := &SNSSubscription{
 				Endpoint:        aws.StringValue(sub.Endpoint),
@@ -183,19 +186,15 @@ func LoadSNSSubscriptions(input *sns.ListSubscriptionsInput) (map[string]*SNSSub
 
 }
 
-func (t *SNSTopic) Poll() []chan error {
+func (t *SNSTopic) Poll(metrics *MetricFetcher) []chan error {
 
-	var errs []chan error
 	t.Stats = &TopicStats{}
 
 	for _, m := range SNSTopicMetrics {
-		m := m
-		errs = append(errs, t.Region.Throttle.do(t.Name+":"+*m.name+" SNSTopic METRICS POLL", func() error {
-			return m.RunFor(t)
-		}))
+		metrics.Add(m.Query(t))
 	}
 
-	return errs
+	return nil
 
 }
 
@@ -1,8 +1,6 @@
 package window
 
 import (
-	"time"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
@@ -68,27 +66,18 @@ var (
 	}
 )
 
-func (m *snsmetric) RunFor(t *SNSTopic) error {
-
-	resp, err := CloudWatchClient.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
-		StartTime: aws.Time(time.Now().Add(-PeriodInMinutes * time.Minute)),
-		EndTime:   aws.Time(time.Now()),
-		Period:    aws.Int64(PeriodInMinutes * 60),
-		Namespace: aws.String("AWS/SNS"),
-		Dimensions: []*cloudwatch.Dimension{
-			{
-				Name:  aws.String("TopicName"),
-				Value: aws.String(t.TopicName),
-			},
-		},
-		MetricName: m.name,
+func (m *snsmetric) Query(t *SNSTopic) *MetricQuery {
+	return &MetricQuery{
+		Namespace: "AWS/SNS",
+		Name:      m.name,
+		Dimensions: []*cloudwatch.Dimension{{
+			Name:  aws.String("TopicName"),
+			Value: aws.String(t.TopicName),
+		}},
 		Statistics: m.statistics,
 		Unit:       m.unit, // fuck this in teh face
-	})
-	if err == nil && len(resp.Datapoints) > 0 {
-		m.processor(t.Stats, resp.Datapoints[0])
+		processor: func(point *cloudwatch.Datapoint) {
+			m.processor(t.Stats, point)
+		},
 	}
-
-	return err
-
 }
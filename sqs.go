@@ -30,10 +30,12 @@ type (
 
 		Name             string
 		Id               string
+		AccountId        string
 		State            string
 		Region           *Region
 		Policy           *SQSPolicy
 		Stats            *QueueStats
+		Metrics          map[string]*Series
 		CloudWatchAlarms []*CloudWatchAlarm
 	}
 
@@ -64,11 +66,11 @@ func (a SQSQueueByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadSQSQueues(input *sqs.ListQueuesInput) (map[string]*SQSQueue, error) {
+func LoadSQSQueues(client SQSAPI, input *sqs.ListQueuesInput) (map[string]*SQSQueue, error) {
 
 	sqss := map[string]*SQSQueue{}
 
-	resp, err := SQSClient.ListQueues(nil)
+	resp, err := client.ListQueues(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +81,7 @@ func LoadSQSQueues(input *sqs.ListQueuesInput) (map[string]*SQSQueue, error) {
 				QueueUrl: *queueUrl,
 				Name:     filepath.Base(*queueUrl),
 			}
-			resp, err := SQSClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+			resp, err := client.GetQueueAttributes(&sqs.GetQueueAttributesInput{
 				AttributeNames: []*string{aws.String("All")},
 				QueueUrl:       queueUrl,
 			})
@@ -135,19 +137,15 @@ func LoadSQSQueues(input *sqs.ListQueuesInput) (map[string]*SQSQueue, error) {
 
 }
 
-func (s *SQSQueue) Poll() []chan error {
+func (s *SQSQueue) Poll(metrics *MetricFetcher) []chan error {
 
-	var errs []chan error
 	s.Stats = &QueueStats{}
 
 	for _, m := range SQSQueueMetrics {
-		m := m
-		errs = append(errs, s.Region.Throttle.do(s.Name+":"+*m.name+" SQSQueue METRICS POLL", func() error {
-			return m.RunFor(s)
-		}))
+		metrics.Add(m.Query(s))
 	}
 
-	return errs
+	return nil
 
 }
 
@@ -1,8 +1,6 @@
 package window
 
 import (
-	"time"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
@@ -89,27 +87,18 @@ var (
 	}
 )
 
-func (m *sqsmetric) RunFor(s *SQSQueue) error {
-
-	resp, err := CloudWatchClient.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
-		StartTime: aws.Time(time.Now().Add(-PeriodInMinutes * time.Minute)),
-		EndTime:   aws.Time(time.Now()),
-		Period:    aws.Int64(PeriodInMinutes * 60),
-		Namespace: aws.String("AWS/SQS"),
-		Dimensions: []*cloudwatch.Dimension{
-			{
-				Name:  aws.String("QueueName"),
-				Value: aws.String(s.Name),
-			},
-		},
-		MetricName: m.name,
+func (m *sqsmetric) Query(s *SQSQueue) *MetricQuery {
+	return &MetricQuery{
+		Namespace: "AWS/SQS",
+		Name:      m.name,
+		Dimensions: []*cloudwatch.Dimension{{
+			Name:  aws.String("QueueName"),
+			Value: aws.String(s.Name),
+		}},
 		Statistics: m.statistics,
 		Unit:       m.unit, // fuck this in teh face
-	})
-	if err == nil && len(resp.Datapoints) > 0 {
-		m.processor(s.Stats, resp.Datapoints[0])
+		processor: func(point *cloudwatch.Datapoint) {
+			m.processor(s.Stats, point)
+		},
 	}
-
-	return err
-
 }
@@ -0,0 +1,223 @@
+package window
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Store keeps a gzipped snapshot of each refresh of a region in
+	// Dir/<region key>/<unix nano>.json.gz, for browsing past states.
+	Store struct {
+		Dir string
+
+		// snapshots older than Retention are removed when saving, as are
+		// the oldest beyond MaxSnapshots per region.  Zero keeps all.
+		Retention    time.Duration
+		MaxSnapshots int
+
+		// recently decoded snapshots, by path
+		cache      map[string]*Region
+		cacheOrder []string
+		me         sync.Mutex
+	}
+
+	TimeAsc []time.Time
+)
+
+const (
+	snapshotExt     = ".json.gz"
+	storeCacheSize  = 8
+	snapshotTimeFmt = "%019d"
+)
+
+func NewStore(dir string, retention time.Duration, max int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		Dir:          dir,
+		Retention:    retention,
+		MaxSnapshots: max,
+		cache:        map[string]*Region{},
+	}, nil
+}
+
+// Save writes a snapshot of the region's last refresh, if not already saved.
+// The caller must hold the region's lock.
+func (s *Store) Save(region *Region) error {
+
+	if region.Refreshed.IsZero() {
+		return nil
+	}
+
+	dir := filepath.Join(s.Dir, region.Key())
+	path := filepath.Join(dir, fmt.Sprintf(snapshotTimeFmt, region.Refreshed.UnixNano())+snapshotExt)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := WriteSnapshot(path, region); err != nil {
+		return err
+	}
+
+	return s.prune(region.Key())
+
+}
+
+// Times returns the times of the region's snapshots, oldest first
+func (s *Store) Times(key string) ([]time.Time, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, key, "*"+snapshotExt))
+	if err != nil {
+		return nil, err
+	}
+	var times []time.Time
+	for _, path := range paths {
+		n, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(path), snapshotExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		times = append(times, time.Unix(0, n))
+	}
+	sort.Sort(TimeAsc(times))
+	return times, nil
+}
+
+// Load returns the region as of its last snapshot at or before at
+func (s *Store) Load(key string, at time.Time) (*Region, error) {
+
+	times, err := s.Times(key)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(times), func(i int) bool { return times[i].After(at) })
+	if i == 0 {
+		return nil, fmt.Errorf("no snapshot of %s at %s", key, at.Format(time.RFC3339))
+	}
+
+	return s.load(filepath.Join(s.Dir, key, fmt.Sprintf(snapshotTimeFmt, times[i-1].UnixNano())+snapshotExt))
+
+}
+
+func (s *Store) load(path string) (*Region, error) {
+
+	s.me.Lock()
+	defer s.me.Unlock()
+
+	if region, exists := s.cache[path]; exists {
+		return region, nil
+	}
+
+	region, err := ReadSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache[path] = region
+	s.cacheOrder = append(s.cacheOrder, path)
+	if len(s.cacheOrder) > storeCacheSize {
+		delete(s.cache, s.cacheOrder[0])
+		s.cacheOrder = s.cacheOrder[1:]
+	}
+
+	return region, nil
+
+}
+
+func (s *Store) prune(key string) error {
+
+	times, err := s.Times(key)
+	if err != nil {
+		return err
+	}
+
+	var remove []time.Time
+	if s.Retention > 0 {
+		cutoff := time.Now().Add(-s.Retention)
+		for len(times) > 0 && times[0].Before(cutoff) {
+			remove, times = append(remove, times[0]), times[1:]
+		}
+	}
+	if s.MaxSnapshots > 0 && len(times) > s.MaxSnapshots {
+		n := len(times) - s.MaxSnapshots
+		remove, times = append(remove, times[:n]...), times[n:]
+	}
+
+	for _, t := range remove {
+		path := filepath.Join(s.Dir, key, fmt.Sprintf(snapshotTimeFmt, t.UnixNano())+snapshotExt)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// WriteSnapshot encodes the region to path, gzipped if path ends in .gz.
+// The caller must hold the region's lock.
+func WriteSnapshot(path string, region *Region) error {
+
+	data, err := EncodeSnapshot(region)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	// write then rename so readers never see a partial snapshot
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+
+}
+
+// ReadSnapshot decodes a snapshot file, gzipped or not
+func ReadSnapshot(path string) (*Region, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = ioutil.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	region, err := DecodeSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return region, nil
+}
+
+func (a TimeAsc) Len() int           { return len(a) }
+func (a TimeAsc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a TimeAsc) Less(i, j int) bool { return a[i].Before(a[j]) }
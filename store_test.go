@@ -0,0 +1,64 @@
+package window
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewStore(dir, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	region := newFakeRegion()
+	defer region.Throttle.stop()
+
+	var times []time.Time
+	for i := 0; i < 3; i++ {
+		if err := region.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Save(region); err != nil {
+			t.Fatal(err)
+		}
+		times = append(times, region.Refreshed)
+	}
+
+	saved, err := store.Times(region.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 || !saved[0].Equal(times[1]) || !saved[1].Equal(times[2]) {
+		t.Fatalf("Expected the oldest snapshot to be pruned, got %v", saved)
+	}
+
+	if _, err := store.Load(region.Key(), times[0]); err == nil {
+		t.Error("Expected no snapshot at pruned time")
+	}
+
+	loaded, err := store.Load(region.Key(), times[2].Add(-time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Refreshed.Equal(times[1]) {
+		t.Errorf("Expected snapshot at %s, got %s", times[1], loaded.Refreshed)
+	}
+	if len(loaded.Instances) != 2 {
+		t.Errorf("Expected 2 instances, got %d", len(loaded.Instances))
+	}
+
+	if again, _ := store.Load(region.Key(), time.Now()); again == loaded {
+		t.Error("Expected latest snapshot, got cached earlier one")
+	}
+
+}
@@ -40,6 +40,7 @@ type (
 		// new props
 		Name             string
 		Id               string
+		AccountId        string
 		CIDR             *net.IPNet
 		VPC              *VPC
 		AvailabilityZone *AvailabilityZone
@@ -71,9 +72,9 @@ func (a SubnetByCIDRAsc) Less(i, j int) bool {
 	return cidr_less_than(a[i].CIDR, a[j].CIDR)
 }
 
-func LoadSubnets(input *ec2.DescribeSubnetsInput) (map[string]*Subnet, error) {
+func LoadSubnets(client EC2API, input *ec2.DescribeSubnetsInput) (map[string]*Subnet, error) {
 
-	resp, err := EC2Client.DescribeSubnets(input)
+	resp, err := client.DescribeSubnets(input)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,263 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Containers reads the cpu, memory and io accounting of each container's
+	// cgroup, under cgroup v1 or v2, resolving docker containers' names and
+	// images if the login user can reach the docker socket
+	Containers struct {
+		CgroupVersion int
+		Cgroups       []Cgroup
+	}
+
+	Cgroup struct {
+		// relative to the hierarchy's root
+		Path string
+		// container id, found in the path
+		Id    string
+		Name  string
+		Image string
+
+		// cpu time used in ns
+		CPUUsage    uint64
+		MemoryUsage uint64
+		// 0 if unlimited
+		MemoryLimit uint64
+		ReadBytes   uint64
+		WriteBytes  uint64
+	}
+
+	ContainerSummary struct {
+		Id    string
+		Name  string
+		Image string
+		// of one cpu, so up to the number of cpus
+		PercentCPU          float64
+		MemoryUsage         uint64
+		MemoryLimit         uint64
+		PercentMemory       float64
+		ReadBytesPerSecond  uint64
+		WriteBytesPerSecond uint64
+	}
+
+	// ContainersSummary holds each container's usage, most memory first
+	ContainersSummary []ContainerSummary
+
+	dockerContainer struct {
+		Id    string
+		Names []string
+		Image string
+	}
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+
+	ContainersCommand = `( ids='/[^/]*[0-9a-f]{64}[^/]*$'; ` +
+		`if [ -f ` + cgroupRoot + `/cgroup.controllers ]; then ` +
+		`echo "version 2"; ` +
+		`for d in $(find ` + cgroupRoot + ` -maxdepth 6 -type d | grep -E "$ids"); do ` +
+		`echo "cgroup ${d#` + cgroupRoot + `}"; sed 's/^/cpu.stat /' $d/cpu.stat; ` +
+		`echo "memory.current $(cat $d/memory.current)"; echo "memory.max $(cat $d/memory.max)"; sed 's/^/io.stat /' $d/io.stat; ` +
+		`done; ` +
+		`else ` +
+		`echo "version 1"; ` +
+		`for d in $(find ` + cgroupRoot + `/memory -maxdepth 6 -type d | grep -E "$ids"); do ` +
+		`p=${d#` + cgroupRoot + `/memory}; echo "cgroup $p"; ` +
+		`echo "cpuacct.usage $(cat ` + cgroupRoot + `/cpuacct$p/cpuacct.usage)"; ` +
+		`echo "memory.usage_in_bytes $(cat $d/memory.usage_in_bytes)"; echo "memory.limit_in_bytes $(cat $d/memory.limit_in_bytes)"; ` +
+		`sed 's/^/blkio /' ` + cgroupRoot + `/blkio$p/blkio.throttle.io_service_bytes; ` +
+		`done; ` +
+		`fi; ` +
+		`[ -w /var/run/docker.sock ] && echo "docker $(curl -s --unix-socket /var/run/docker.sock http://localhost/containers/json)"; ` +
+		`true ) 2>/dev/null`
+)
+
+var containerIdRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func init() {
+	Register("containers", func(map[string]string) (Metric, error) {
+		return new(Containers), nil
+	})
+}
+
+func (_ *Containers) Command() string {
+	return ContainersCommand
+}
+
+func (c *Containers) Parse(b []byte) error {
+
+	// output (v2):
+	// version 2
+	// cgroup /system.slice/docker-3f4e...c1.scope
+	// cpu.stat usage_usec 1234567
+	// memory.current 52428800
+	// memory.max max
+	// io.stat 259:0 rbytes=1048576 wbytes=4096 rios=20 wios=1 dbytes=0 dios=0
+	// docker [{"Id":"3f4e...c1","Names":["/web"],"Image":"nginx:1.25"}]
+	//
+	// output (v1):
+	// version 1
+	// cgroup /docker/3f4e...c1
+	// cpuacct.usage 1234567000
+	// memory.usage_in_bytes 52428800
+	// memory.limit_in_bytes 9223372036854771712
+	// blkio 202:0 Read 1048576
+
+	var (
+		cg      *Cgroup
+		dockers []dockerContainer
+	)
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1<<20)
+
+	for s.Scan() {
+
+		line := s.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		if fields[0] == "version" {
+			c.CgroupVersion, _ = strconv.Atoi(fields[1])
+			continue
+		}
+		if fields[0] == "docker" {
+			json.Unmarshal([]byte(line[len("docker "):]), &dockers)
+			continue
+		}
+		if fields[0] == "cgroup" {
+			c.Cgroups = append(c.Cgroups, Cgroup{
+				Path: fields[1],
+				Id:   containerIdRegexp.FindString(fields[1]),
+			})
+			cg = &c.Cgroups[len(c.Cgroups)-1]
+			continue
+		}
+		if cg == nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "cpu.stat":
+			if len(fields) > 2 && fields[1] == "usage_usec" {
+				usec, _ := strconv.ParseUint(fields[2], 10, 64)
+				cg.CPUUsage = usec * 1000
+			}
+		case "cpuacct.usage":
+			cg.CPUUsage, _ = strconv.ParseUint(fields[1], 10, 64)
+		case "memory.current", "memory.usage_in_bytes":
+			cg.MemoryUsage, _ = strconv.ParseUint(fields[1], 10, 64)
+		case "memory.max", "memory.limit_in_bytes":
+			// v1's unlimited is the largest page aligned int64
+			if limit, err := strconv.ParseUint(fields[1], 10, 64); err == nil && limit < 1<<62 {
+				cg.MemoryLimit = limit
+			}
+		case "io.stat":
+			for _, field := range fields[2:] {
+				kv := strings.SplitN(field, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				n, _ := strconv.ParseUint(kv[1], 10, 64)
+				switch kv[0] {
+				case "rbytes":
+					cg.ReadBytes += n
+				case "wbytes":
+					cg.WriteBytes += n
+				}
+			}
+		case "blkio":
+			if len(fields) > 3 {
+				n, _ := strconv.ParseUint(fields[3], 10, 64)
+				switch fields[2] {
+				case "Read":
+					cg.ReadBytes += n
+				case "Write":
+					cg.WriteBytes += n
+				}
+			}
+		}
+
+	}
+
+	for i := range c.Cgroups {
+		for _, d := range dockers {
+			if d.Id == c.Cgroups[i].Id {
+				if len(d.Names) > 0 {
+					c.Cgroups[i].Name = strings.TrimPrefix(d.Names[0], "/")
+				}
+				c.Cgroups[i].Image = d.Image
+				break
+			}
+		}
+	}
+
+	return s.Err()
+
+}
+
+// Summarize computes each container's usage since the previous sample
+func (c *Containers) Summarize(prev Metric, duration time.Duration) interface{} {
+
+	last := map[string]*Cgroup{}
+	if p, ok := prev.(*Containers); ok && p != nil {
+		for i := range p.Cgroups {
+			last[p.Cgroups[i].Path] = &p.Cgroups[i]
+		}
+	}
+
+	seconds := duration.Seconds()
+
+	summary := ContainersSummary{}
+
+	for _, cg := range c.Cgroups {
+
+		cs := ContainerSummary{
+			Id:          cg.Id,
+			Name:        cg.Name,
+			Image:       cg.Image,
+			MemoryUsage: cg.MemoryUsage,
+			MemoryLimit: cg.MemoryLimit,
+		}
+		if len(cs.Id) > 12 {
+			cs.Id = cs.Id[:12]
+		}
+		if cg.MemoryLimit > 0 {
+			cs.PercentMemory = float64(cg.MemoryUsage) / float64(cg.MemoryLimit)
+		}
+
+		// a restarted container's counters start over
+		if p, exists := last[cg.Path]; exists && seconds > 0 && cg.CPUUsage >= p.CPUUsage {
+			cs.PercentCPU = float64(cg.CPUUsage-p.CPUUsage) / float64(time.Second) / seconds
+			if cg.ReadBytes >= p.ReadBytes {
+				cs.ReadBytesPerSecond = uint64(float64(cg.ReadBytes-p.ReadBytes) / seconds)
+			}
+			if cg.WriteBytes >= p.WriteBytes {
+				cs.WriteBytesPerSecond = uint64(float64(cg.WriteBytes-p.WriteBytes) / seconds)
+			}
+		}
+
+		summary = append(summary, cs)
+
+	}
+
+	sort.SliceStable(summary, func(i, j int) bool {
+		return summary[i].MemoryUsage > summary[j].MemoryUsage
+	})
+
+	return summary
+
+}
@@ -0,0 +1,94 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseContainers(t *testing.T) {
+
+	const (
+		web = "3f4e5d6c7b8a99887766554433221100ffeeddccbbaa00112233445566778899"
+		job = "aa11bb22cc33dd44ee55ff6600112233445566778899aabbccddeeff00112233"
+
+		output_v2 = `version 2
+cgroup /system.slice/docker-` + web + `.scope
+cpu.stat usage_usec 1500000
+cpu.stat user_usec 1000000
+cpu.stat system_usec 500000
+memory.current 52428800
+memory.max 104857600
+io.stat 259:0 rbytes=1048576 wbytes=4096 rios=20 wios=1 dbytes=0 dios=0
+io.stat 259:1 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0
+cgroup /kubepods.slice/kubepods-besteffort.slice/cri-containerd-` + job + `.scope
+cpu.stat usage_usec 250000
+memory.current 209715200
+memory.max max
+docker [{"Id":"` + web + `","Names":["/web"],"Image":"nginx:1.25","State":"running"}]
+`
+		output_v1 = `version 1
+cgroup /docker/` + web + `
+cpuacct.usage 1500000000
+memory.usage_in_bytes 52428800
+memory.limit_in_bytes 9223372036854771712
+blkio 202:0 Read 1048576
+blkio 202:0 Write 4096
+blkio 202:0 Sync 4096
+blkio 202:0 Async 1048576
+blkio 202:0 Total 1052672
+blkio Total 1052672
+`
+	)
+
+	expected_v2 := Containers{
+		CgroupVersion: 2,
+		Cgroups: []Cgroup{
+			{Path: "/system.slice/docker-" + web + ".scope", Id: web, Name: "web", Image: "nginx:1.25", CPUUsage: 1500000000, MemoryUsage: 52428800, MemoryLimit: 104857600, ReadBytes: 1049600, WriteBytes: 4096},
+			{Path: "/kubepods.slice/kubepods-besteffort.slice/cri-containerd-" + job + ".scope", Id: job, CPUUsage: 250000000, MemoryUsage: 209715200},
+		},
+	}
+
+	var v2 Containers
+	if err := v2.Parse([]byte(output_v2)); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(v2, expected_v2) {
+		t.Error("v2 parse mismatch")
+		dumpDiff(expected_v2, v2)
+	}
+
+	expected_v1 := Containers{
+		CgroupVersion: 1,
+		Cgroups: []Cgroup{
+			{Path: "/docker/" + web, Id: web, CPUUsage: 1500000000, MemoryUsage: 52428800, ReadBytes: 1048576, WriteBytes: 4096},
+		},
+	}
+
+	var v1 Containers
+	if err := v1.Parse([]byte(output_v1)); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(v1, expected_v1) {
+		t.Error("v1 parse mismatch")
+		dumpDiff(expected_v1, v1)
+	}
+
+	next := &Containers{CgroupVersion: 2, Cgroups: append([]Cgroup{}, v2.Cgroups...)}
+	next.Cgroups[0].CPUUsage += uint64(time.Second)
+	next.Cgroups[0].ReadBytes += 8192
+	next.Cgroups[1].CPUUsage += uint64(4 * time.Second)
+
+	summary := next.Summarize(&v2, 2*time.Second).(ContainersSummary)
+
+	expected_summary := ContainersSummary{
+		{Id: job[:12], PercentCPU: 2, MemoryUsage: 209715200},
+		{Id: web[:12], Name: "web", Image: "nginx:1.25", PercentCPU: 0.5, MemoryUsage: 52428800, MemoryLimit: 104857600, PercentMemory: 0.5, ReadBytesPerSecond: 4096},
+	}
+
+	if !reflect.DeepEqual(summary, expected_summary) {
+		t.Error("summary mismatch")
+		dumpDiff(expected_summary, summary)
+	}
+
+}
@@ -0,0 +1,189 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// DiskStats holds the io counters of each block device
+	DiskStats struct {
+		Devices []BlockDevice
+	}
+
+	BlockDevice struct {
+		Major uint64
+		Minor uint64
+		Name  string
+		// nvme serial, the volume id without its dash on ebs
+		Serial string
+
+		ReadsCompleted  uint64
+		ReadsMerged     uint64
+		SectorsRead     uint64
+		ReadTime        uint64 // ms
+		WritesCompleted uint64
+		WritesMerged    uint64
+		SectorsWritten  uint64
+		WriteTime       uint64 // ms
+		InProgress      uint64
+		IOTime          uint64 // ms
+		WeightedIOTime  uint64 // ms
+	}
+
+	DeviceIOSummary struct {
+		Name                string
+		Serial              string
+		ReadsPerSecond      float64
+		WritesPerSecond     float64
+		ReadBytesPerSecond  uint64
+		WriteBytesPerSecond uint64
+		// average requests queued or in flight
+		QueueDepth float64
+		// average time per request, queueing included
+		Await       time.Duration
+		PercentUtil float64
+	}
+
+	// DiskStatsSummary holds the io rates of each whole device
+	DiskStatsSummary []DeviceIOSummary
+)
+
+const (
+	// diskstats sectors are 512 bytes regardless of the device
+	diskStatsSectorSize = 512
+
+	DiskStatsCommand = `( cat /proc/diskstats; for d in /sys/block/nvme*; do ` +
+		`echo "serial ${d##*/} $(cat $d/device/serial)"; done; true ) 2>/dev/null`
+)
+
+func init() {
+	Register("diskstats", func(map[string]string) (Metric, error) {
+		return new(DiskStats), nil
+	})
+}
+
+func (_ *DiskStats) Command() string {
+	return DiskStatsCommand
+}
+
+func (ds *DiskStats) Parse(b []byte) error {
+
+	// output:
+	//  202       0 xvda 8917 31 567586 4136 23402 9730 1074904 40856 0 22224 44992
+	//  202       1 xvda1 8889 31 565330 4124 23402 9730 1074904 40856 0 22208 44980
+	//  259       0 nvme0n1 1254 0 57338 512 60 31 1392 52 0 568 564 0 0 0 0
+	// serial nvme0n1 vol0a1b2c3d4e5f60718
+
+	serials := map[string]string{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+
+	for s.Scan() {
+
+		fields := strings.Fields(s.Text())
+
+		if len(fields) > 0 && fields[0] == "serial" {
+			if len(fields) > 2 {
+				serials[fields[1]] = fields[2]
+			}
+			continue
+		}
+		if len(fields) < 14 {
+			continue
+		}
+
+		var dev BlockDevice
+		dev.Name = fields[2]
+		for i, v := range []*uint64{
+			&dev.Major, &dev.Minor, nil,
+			&dev.ReadsCompleted, &dev.ReadsMerged, &dev.SectorsRead, &dev.ReadTime,
+			&dev.WritesCompleted, &dev.WritesMerged, &dev.SectorsWritten, &dev.WriteTime,
+			&dev.InProgress, &dev.IOTime, &dev.WeightedIOTime,
+		} {
+			if v != nil {
+				*v, _ = strconv.ParseUint(fields[i], 10, 64)
+			}
+		}
+		ds.Devices = append(ds.Devices, dev)
+
+	}
+
+	for i := range ds.Devices {
+		ds.Devices[i].Serial = serials[ds.Devices[i].Name]
+	}
+
+	return s.Err()
+
+}
+
+// Summarize computes each whole device's io rates since the previous
+// sample.  Partitions and loop and ram devices are left out.
+func (ds *DiskStats) Summarize(prev Metric, duration time.Duration) interface{} {
+
+	last := map[string]*BlockDevice{}
+	if p, ok := prev.(*DiskStats); ok && p != nil {
+		for i := range p.Devices {
+			last[p.Devices[i].Name] = &p.Devices[i]
+		}
+	}
+
+	names := map[string]bool{}
+	for _, dev := range ds.Devices {
+		names[dev.Name] = true
+	}
+
+	seconds := duration.Seconds()
+	ms := seconds * 1000
+
+	summary := DiskStatsSummary{}
+
+	for _, dev := range ds.Devices {
+
+		if strings.HasPrefix(dev.Name, "loop") || strings.HasPrefix(dev.Name, "ram") || isPartition(dev.Name, names) {
+			continue
+		}
+
+		io := DeviceIOSummary{
+			Name:   dev.Name,
+			Serial: dev.Serial,
+		}
+
+		if p, exists := last[dev.Name]; exists && seconds > 0 && dev.ReadsCompleted >= p.ReadsCompleted && dev.WritesCompleted >= p.WritesCompleted {
+			reads := float64(dev.ReadsCompleted - p.ReadsCompleted)
+			writes := float64(dev.WritesCompleted - p.WritesCompleted)
+			io.ReadsPerSecond = reads / seconds
+			io.WritesPerSecond = writes / seconds
+			io.ReadBytesPerSecond = uint64(float64((dev.SectorsRead-p.SectorsRead)*diskStatsSectorSize) / seconds)
+			io.WriteBytesPerSecond = uint64(float64((dev.SectorsWritten-p.SectorsWritten)*diskStatsSectorSize) / seconds)
+			io.QueueDepth = float64(dev.WeightedIOTime-p.WeightedIOTime) / ms
+			io.PercentUtil = float64(dev.IOTime-p.IOTime) / ms
+			if io.PercentUtil > 1 {
+				io.PercentUtil = 1
+			}
+			if ios := reads + writes; ios > 0 {
+				wait := float64((dev.ReadTime - p.ReadTime) + (dev.WriteTime - p.WriteTime))
+				io.Await = time.Duration(wait / ios * float64(time.Millisecond))
+			}
+		}
+
+		summary = append(summary, io)
+
+	}
+
+	return summary
+
+}
+
+// isPartition reports whether name is a numbered partition of another
+// device, e.g. xvda1 of xvda or nvme0n1p1 of nvme0n1
+func isPartition(name string, names map[string]bool) bool {
+	base := strings.TrimRight(name, "0123456789")
+	if base == name {
+		return false
+	}
+	return names[base] || (strings.HasSuffix(base, "p") && names[strings.TrimSuffix(base, "p")])
+}
@@ -0,0 +1,63 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDiskStats(t *testing.T) {
+
+	const output = `   7       0 loop0 51 0 2160 12 0 0 0 0 0 20 12 0 0 0 0
+ 202       0 xvda 8917 31 567586 4136 23402 9730 1074904 40856 0 22224 44992
+ 202       1 xvda1 8889 31 565330 4124 23402 9730 1074904 40856 0 22208 44980
+ 259       0 nvme1n1 1254 0 57338 512 60 31 1392 52 0 568 564 0 0 0 0 0 0
+ 259       1 nvme1n1p1 1200 0 56000 500 60 31 1392 52 0 560 552 0 0 0 0 0 0
+serial nvme1n1 vol0a1b2c3d4e5f60718
+`
+
+	expected := DiskStats{
+		Devices: []BlockDevice{
+			{Major: 7, Minor: 0, Name: "loop0", ReadsCompleted: 51, SectorsRead: 2160, ReadTime: 12, IOTime: 20, WeightedIOTime: 12},
+			{Major: 202, Minor: 0, Name: "xvda", ReadsCompleted: 8917, ReadsMerged: 31, SectorsRead: 567586, ReadTime: 4136, WritesCompleted: 23402, WritesMerged: 9730, SectorsWritten: 1074904, WriteTime: 40856, IOTime: 22224, WeightedIOTime: 44992},
+			{Major: 202, Minor: 1, Name: "xvda1", ReadsCompleted: 8889, ReadsMerged: 31, SectorsRead: 565330, ReadTime: 4124, WritesCompleted: 23402, WritesMerged: 9730, SectorsWritten: 1074904, WriteTime: 40856, IOTime: 22208, WeightedIOTime: 44980},
+			{Major: 259, Minor: 0, Name: "nvme1n1", Serial: "vol0a1b2c3d4e5f60718", ReadsCompleted: 1254, SectorsRead: 57338, ReadTime: 512, WritesCompleted: 60, WritesMerged: 31, SectorsWritten: 1392, WriteTime: 52, IOTime: 568, WeightedIOTime: 564},
+			{Major: 259, Minor: 1, Name: "nvme1n1p1", ReadsCompleted: 1200, SectorsRead: 56000, ReadTime: 500, WritesCompleted: 60, WritesMerged: 31, SectorsWritten: 1392, WriteTime: 52, IOTime: 560, WeightedIOTime: 552},
+		},
+	}
+
+	var ds DiskStats
+	if err := ds.Parse([]byte(output)); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(ds, expected) {
+		t.Error("parse mismatch")
+		dumpDiff(expected, ds)
+	}
+
+	// 2s later xvda has done 100 reads of 4KB and 300 writes of 8KB
+	next := &DiskStats{Devices: append([]BlockDevice{}, ds.Devices...)}
+	xvda := &next.Devices[1]
+	xvda.ReadsCompleted += 100
+	xvda.SectorsRead += 100 * 8
+	xvda.ReadTime += 200
+	xvda.WritesCompleted += 300
+	xvda.SectorsWritten += 300 * 16
+	xvda.WriteTime += 1000
+	xvda.IOTime += 500
+	xvda.WeightedIOTime += 3000
+
+	summary := next.Summarize(&ds, 2*time.Second).(DiskStatsSummary)
+
+	expected_summary := DiskStatsSummary{
+		{Name: "xvda", ReadsPerSecond: 50, WritesPerSecond: 150, ReadBytesPerSecond: 204800, WriteBytesPerSecond: 1228800, QueueDepth: 1.5, Await: 3 * time.Millisecond, PercentUtil: 0.25},
+		{Name: "nvme1n1", Serial: "vol0a1b2c3d4e5f60718"},
+	}
+
+	if !reflect.DeepEqual(summary, expected_summary) {
+		t.Error("summary mismatch")
+		dumpDiff(expected_summary, summary)
+	}
+
+}
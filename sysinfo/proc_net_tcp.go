@@ -0,0 +1,92 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+type (
+	// TCPSockets counts the tcp sockets of /proc/net/tcp and tcp6 by state
+	TCPSockets struct {
+		// by state name, e.g. ESTABLISHED
+		States map[string]int
+		// listening sockets with connections waiting to be accepted,
+		// a sign the accept queue may overflow
+		ListenBacklogged int
+	}
+)
+
+const TCPSocketsCommand = `( cat /proc/net/tcp /proc/net/tcp6; true ) 2>/dev/null`
+
+var (
+	// see include/net/tcp_states.h
+	tcpStates = map[uint64]string{
+		0x01: "ESTABLISHED",
+		0x02: "SYN_SENT",
+		0x03: "SYN_RECV",
+		0x04: "FIN_WAIT1",
+		0x05: "FIN_WAIT2",
+		0x06: "TIME_WAIT",
+		0x07: "CLOSE",
+		0x08: "CLOSE_WAIT",
+		0x09: "LAST_ACK",
+		0x0A: "LISTEN",
+		0x0B: "CLOSING",
+		0x0C: "NEW_SYN_RECV",
+	}
+)
+
+func init() {
+	Register("tcpsockets", func(map[string]string) (Metric, error) {
+		return new(TCPSockets), nil
+	})
+}
+
+func (_ *TCPSockets) Command() string {
+	return TCPSocketsCommand
+}
+
+func (ts *TCPSockets) Parse(b []byte) error {
+
+	// output:
+	//   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+	//    0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 11917 1 0000000000000000 100 0 0 10 0
+	//    1: 0A00020F:0016 0A000201:C5D2 01 00000000:00000000 02:00057B81 00000000     0        0 25143 4 0000000000000000 20 4 31 10 -1
+
+	ts.States = map[string]int{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+
+	for s.Scan() {
+
+		fields := strings.Fields(s.Text())
+		if len(fields) < 5 || fields[0] == "sl" {
+			continue
+		}
+
+		st, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+		state, exists := tcpStates[st]
+		if !exists {
+			continue
+		}
+		ts.States[state]++
+
+		// for listeners rx_queue is the accept queue's length
+		if state == "LISTEN" {
+			if i := strings.IndexByte(fields[4], ':'); i >= 0 {
+				if n, _ := strconv.ParseUint(fields[4][i+1:], 16, 64); n > 0 {
+					ts.ListenBacklogged++
+				}
+			}
+		}
+
+	}
+
+	return s.Err()
+
+}
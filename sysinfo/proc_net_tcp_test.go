@@ -0,0 +1,40 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTCPSockets(t *testing.T) {
+
+	const output = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 11917 1 0000000000000000 100 0 0 10 0
+   1: 00000000:0050 00000000:0000 0A 00000000:00000003 00:00000000 00000000     0        0 11918 1 0000000000000000 100 0 0 10 0
+   2: 0A00020F:0016 0A000201:C5D2 01 00000000:00000000 02:00057B81 00000000     0        0 25143 4 0000000000000000 20 4 31 10 -1
+   3: 0A00020F:0050 0A000201:C5D3 06 00000000:00000000 03:00001770 00000000     0        0 0 3 0000000000000000
+  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 11919 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00000F02000A:0050 0000000000000000FFFF00000102000A:C5D4 08 00000000:00000000 00:00000000 00000000    33        0 25150 1 0000000000000000 20 4 30 10 -1
+`
+
+	expected := TCPSockets{
+		States: map[string]int{
+			"LISTEN":      3,
+			"ESTABLISHED": 1,
+			"TIME_WAIT":   1,
+			"CLOSE_WAIT":  1,
+		},
+		ListenBacklogged: 1,
+	}
+
+	var ts TCPSockets
+	if err := ts.Parse([]byte(output)); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(ts, expected) {
+		t.Error("parse mismatch")
+		dumpDiff(expected, ts)
+	}
+
+}
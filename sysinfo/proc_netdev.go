@@ -0,0 +1,152 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// NetDev holds the counters of each network interface
+	NetDev struct {
+		Interfaces []NetInterface
+	}
+
+	NetInterface struct {
+		Name      string
+		MAC       string
+		RxBytes   uint64
+		RxPackets uint64
+		RxErrors  uint64
+		RxDrops   uint64
+		TxBytes   uint64
+		TxPackets uint64
+		TxErrors  uint64
+		TxDrops   uint64
+	}
+
+	InterfaceSummary struct {
+		Name                string
+		MAC                 string
+		BytesPerSecondIn    uint64
+		BytesPerSecondOut   uint64
+		PacketsPerSecondIn  uint64
+		PacketsPerSecondOut uint64
+		// since the previous sample
+		Errors uint64
+		Drops  uint64
+	}
+
+	// NetDevSummary holds the rates of each interface but loopback
+	NetDevSummary []InterfaceSummary
+)
+
+const NetDevCommand = `( cat /proc/net/dev; for i in /sys/class/net/*; do ` +
+	`echo "mac ${i##*/} $(cat $i/address)"; done; true ) 2>/dev/null`
+
+func init() {
+	Register("netdev", func(map[string]string) (Metric, error) {
+		return new(NetDev), nil
+	})
+}
+
+func (_ *NetDev) Command() string {
+	return NetDevCommand
+}
+
+func (nd *NetDev) Parse(b []byte) error {
+
+	// output:
+	// Inter-|   Receive                                                |  Transmit
+	//  face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+	//     lo:   12345     120    0    0    0     0          0         0    12345     120    0    0    0     0       0          0
+	//   eth0: 9876543   10234    0    2    0     0          0         0  1234567    8765    0    0    0     0       0          0
+	// mac eth0 0a:1b:2c:3d:4e:5f
+
+	macs := map[string]string{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+
+	for s.Scan() {
+
+		line := s.Text()
+
+		if strings.HasPrefix(line, "mac ") {
+			if fields := strings.Fields(line); len(fields) > 2 {
+				macs[fields[1]] = fields[2]
+			}
+			continue
+		}
+
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 || strings.Contains(line, "|") {
+			continue
+		}
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) < 12 {
+			continue
+		}
+
+		iface := NetInterface{Name: strings.TrimSpace(line[:colon])}
+		for i, v := range map[int]*uint64{
+			0: &iface.RxBytes, 1: &iface.RxPackets, 2: &iface.RxErrors, 3: &iface.RxDrops,
+			8: &iface.TxBytes, 9: &iface.TxPackets, 10: &iface.TxErrors, 11: &iface.TxDrops,
+		} {
+			*v, _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		nd.Interfaces = append(nd.Interfaces, iface)
+
+	}
+
+	for i := range nd.Interfaces {
+		nd.Interfaces[i].MAC = macs[nd.Interfaces[i].Name]
+	}
+
+	return s.Err()
+
+}
+
+// Summarize computes each interface's rates since the previous sample
+func (nd *NetDev) Summarize(prev Metric, duration time.Duration) interface{} {
+
+	last := map[string]*NetInterface{}
+	if p, ok := prev.(*NetDev); ok && p != nil {
+		for i := range p.Interfaces {
+			last[p.Interfaces[i].Name] = &p.Interfaces[i]
+		}
+	}
+
+	seconds := duration.Seconds()
+
+	summary := NetDevSummary{}
+
+	for _, iface := range nd.Interfaces {
+
+		if iface.Name == "lo" {
+			continue
+		}
+
+		is := InterfaceSummary{
+			Name: iface.Name,
+			MAC:  iface.MAC,
+		}
+
+		// counters reset if the interface is recreated
+		if p, exists := last[iface.Name]; exists && seconds > 0 && iface.RxBytes >= p.RxBytes && iface.TxBytes >= p.TxBytes {
+			is.BytesPerSecondIn = uint64(float64(iface.RxBytes-p.RxBytes) / seconds)
+			is.BytesPerSecondOut = uint64(float64(iface.TxBytes-p.TxBytes) / seconds)
+			is.PacketsPerSecondIn = uint64(float64(iface.RxPackets-p.RxPackets) / seconds)
+			is.PacketsPerSecondOut = uint64(float64(iface.TxPackets-p.TxPackets) / seconds)
+			is.Errors = (iface.RxErrors + iface.TxErrors) - (p.RxErrors + p.TxErrors)
+			is.Drops = (iface.RxDrops + iface.TxDrops) - (p.RxDrops + p.TxDrops)
+		}
+
+		summary = append(summary, is)
+
+	}
+
+	return summary
+
+}
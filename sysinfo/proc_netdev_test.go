@@ -0,0 +1,55 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseNetDev(t *testing.T) {
+
+	const output = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:   12345     120    0    0    0     0          0         0    12345     120    0    0    0     0       0          0
+  eth0: 9876543   10234    1    2    0     0          0         0  1234567    8765    3    4    0     0       0          0
+mac eth0 0a:1b:2c:3d:4e:5f
+mac lo 00:00:00:00:00:00
+`
+
+	expected := NetDev{
+		Interfaces: []NetInterface{
+			{Name: "lo", MAC: "00:00:00:00:00:00", RxBytes: 12345, RxPackets: 120, TxBytes: 12345, TxPackets: 120},
+			{Name: "eth0", MAC: "0a:1b:2c:3d:4e:5f", RxBytes: 9876543, RxPackets: 10234, RxErrors: 1, RxDrops: 2, TxBytes: 1234567, TxPackets: 8765, TxErrors: 3, TxDrops: 4},
+		},
+	}
+
+	var nd NetDev
+	if err := nd.Parse([]byte(output)); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(nd, expected) {
+		t.Error("parse mismatch")
+		dumpDiff(expected, nd)
+	}
+
+	next := &NetDev{Interfaces: append([]NetInterface{}, nd.Interfaces...)}
+	eth0 := &next.Interfaces[1]
+	eth0.RxBytes += 20000
+	eth0.RxPackets += 20
+	eth0.RxDrops++
+	eth0.TxBytes += 4000
+	eth0.TxPackets += 10
+
+	summary := next.Summarize(&nd, 2*time.Second).(NetDevSummary)
+
+	expected_summary := NetDevSummary{
+		{Name: "eth0", MAC: "0a:1b:2c:3d:4e:5f", BytesPerSecondIn: 10000, BytesPerSecondOut: 2000, PacketsPerSecondIn: 10, PacketsPerSecondOut: 5, Drops: 1},
+	}
+
+	if !reflect.DeepEqual(summary, expected_summary) {
+		t.Error("summary mismatch")
+		dumpDiff(expected_summary, summary)
+	}
+
+}
@@ -0,0 +1,272 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// ProcInfo samples every process from /proc/[pid]
+	ProcInfo struct {
+		// clock ticks per second of the process cpu times
+		ClockTicks uint64
+		Processes  []Process
+
+		// processes kept in each list of the summary
+		top int
+	}
+
+	Process struct {
+		PID       uint64
+		PPID      uint64
+		Name      string
+		Cmdline   string
+		State     string
+		UID       uint64
+		Threads   uint64
+		StartTime uint64
+		// user and system cpu time in clock ticks
+		UTime uint64
+		STime uint64
+		// resident memory in bytes
+		RSS uint64
+		// storage io in bytes, only readable for the login user's processes
+		// unless it is root
+		ReadBytes  uint64
+		WriteBytes uint64
+	}
+
+	ProcessSummary struct {
+		PID     uint64
+		Name    string
+		Cmdline string
+		State   string
+		UID     uint64
+		// of one cpu, so up to the number of cpus
+		PercentCPU          float64
+		RSS                 uint64
+		ReadBytesPerSecond  uint64
+		WriteBytesPerSecond uint64
+	}
+
+	// ProcInfoSummary holds the top processes by cpu, memory and io
+	ProcInfoSummary struct {
+		Total int
+		ByCPU []ProcessSummary
+		ByRSS []ProcessSummary
+		ByIO  []ProcessSummary
+	}
+)
+
+const (
+	DefaultTopProcesses = 10
+
+	// processes can exit while being read, so errors are ignored
+	ProcInfoCommand = `( getconf CLK_TCK; for d in /proc/[0-9]*; do ` +
+		`echo "pid ${d#/proc/}"; cat $d/stat; grep -E '^(Uid|VmRSS):' $d/status; grep -E '^(read|write)_bytes:' $d/io; ` +
+		`echo "cmdline $(tr '\0' ' ' < $d/cmdline)"; done; true ) 2>/dev/null`
+)
+
+func init() {
+	Register("procinfo", func(options map[string]string) (Metric, error) {
+		pi := &ProcInfo{top: DefaultTopProcesses}
+		if top, exists := options["top"]; exists {
+			n, err := strconv.Atoi(top)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid top %q", top)
+			}
+			pi.top = n
+		}
+		return pi, nil
+	})
+}
+
+func (_ *ProcInfo) Command() string {
+	return ProcInfoCommand
+}
+
+func (pi *ProcInfo) Parse(b []byte) error {
+
+	// output:
+	// 100
+	// pid 1
+	// 1 (systemd) S 0 1 1 0 -1 4194560 ... 0 0 20 0 1 0 4 ...
+	// Uid:	0	0	0	0
+	// VmRSS:	   11520 kB
+	// read_bytes: 251392000
+	// write_bytes: 48685056
+	// cmdline /sbin/init splash
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1<<20)
+
+	var proc *Process
+
+	if s.Scan() {
+		var err error
+		if pi.ClockTicks, err = strconv.ParseUint(strings.TrimSpace(s.Text()), 10, 64); err != nil {
+			return fmt.Errorf("clock ticks: %v", err)
+		}
+	}
+
+	for s.Scan() {
+
+		line := s.Text()
+
+		if strings.HasPrefix(line, "pid ") {
+			pi.Processes = append(pi.Processes, Process{})
+			proc = &pi.Processes[len(pi.Processes)-1]
+			proc.PID, _ = strconv.ParseUint(line[len("pid "):], 10, 64)
+			continue
+		}
+		if proc == nil {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch {
+		case strings.HasPrefix(line, "cmdline "):
+			proc.Cmdline = strings.TrimSpace(line[len("cmdline "):])
+		case strings.HasPrefix(line, "Uid:"):
+			if len(fields) > 1 {
+				proc.UID, _ = strconv.ParseUint(fields[1], 10, 64)
+			}
+		case strings.HasPrefix(line, "VmRSS:"):
+			if len(fields) > 1 {
+				proc.RSS, _ = strconv.ParseUint(fields[1], 10, 64)
+				proc.RSS *= 1024
+			}
+		case strings.HasPrefix(line, "read_bytes:"):
+			if len(fields) > 1 {
+				proc.ReadBytes, _ = strconv.ParseUint(fields[1], 10, 64)
+			}
+		case strings.HasPrefix(line, "write_bytes:"):
+			if len(fields) > 1 {
+				proc.WriteBytes, _ = strconv.ParseUint(fields[1], 10, 64)
+			}
+		case strings.HasPrefix(line, fmt.Sprintf("%d (", proc.PID)):
+			parseProcStat(proc, line)
+		}
+
+	}
+
+	// drop processes that exited before their stat was read
+	procs := pi.Processes[:0]
+	for _, proc := range pi.Processes {
+		if len(proc.Name) > 0 {
+			procs = append(procs, proc)
+		}
+	}
+	pi.Processes = procs
+
+	return s.Err()
+
+}
+
+func parseProcStat(proc *Process, line string) {
+
+	// the name is in parens and may itself contain spaces and parens
+	lparen, rparen := strings.IndexByte(line, '('), strings.LastIndexByte(line, ')')
+	if lparen < 0 || rparen < lparen {
+		return
+	}
+	proc.Name = line[lparen+1 : rparen]
+
+	// fields from state on, see proc(5)
+	fields := strings.Fields(line[rparen+1:])
+	if len(fields) < 20 {
+		return
+	}
+	proc.State = fields[0]
+	proc.PPID, _ = strconv.ParseUint(fields[1], 10, 64)
+	proc.UTime, _ = strconv.ParseUint(fields[11], 10, 64)
+	proc.STime, _ = strconv.ParseUint(fields[12], 10, 64)
+	proc.Threads, _ = strconv.ParseUint(fields[17], 10, 64)
+	proc.StartTime, _ = strconv.ParseUint(fields[19], 10, 64)
+
+}
+
+// Summarize ranks processes by cpu and io over the time since the
+// previous sample, matching processes by pid and start time so reused
+// pids aren't compared.  Processes new since then are ranked on memory
+// only.
+func (pi *ProcInfo) Summarize(prev Metric, duration time.Duration) interface{} {
+
+	type key struct{ pid, start uint64 }
+
+	last := map[key]*Process{}
+	if p, ok := prev.(*ProcInfo); ok && p != nil {
+		for i := range p.Processes {
+			proc := &p.Processes[i]
+			last[key{proc.PID, proc.StartTime}] = proc
+		}
+	}
+
+	seconds := duration.Seconds()
+
+	procs := make([]ProcessSummary, len(pi.Processes))
+	for i, proc := range pi.Processes {
+		ps := ProcessSummary{
+			PID:     proc.PID,
+			Name:    proc.Name,
+			Cmdline: proc.Cmdline,
+			State:   proc.State,
+			UID:     proc.UID,
+			RSS:     proc.RSS,
+		}
+		if p, exists := last[key{proc.PID, proc.StartTime}]; exists && seconds > 0 && pi.ClockTicks > 0 {
+			ticks := (proc.UTime + proc.STime) - (p.UTime + p.STime)
+			ps.PercentCPU = float64(ticks) / float64(pi.ClockTicks) / seconds
+			if proc.ReadBytes >= p.ReadBytes {
+				ps.ReadBytesPerSecond = uint64(float64(proc.ReadBytes-p.ReadBytes) / seconds)
+			}
+			if proc.WriteBytes >= p.WriteBytes {
+				ps.WriteBytesPerSecond = uint64(float64(proc.WriteBytes-p.WriteBytes) / seconds)
+			}
+		}
+		procs[i] = ps
+	}
+
+	top := pi.top
+	if top == 0 {
+		top = DefaultTopProcesses
+	}
+
+	return &ProcInfoSummary{
+		Total: len(procs),
+		ByCPU: topProcesses(procs, top, func(ps *ProcessSummary) float64 { return ps.PercentCPU }),
+		ByRSS: topProcesses(procs, top, func(ps *ProcessSummary) float64 { return float64(ps.RSS) }),
+		ByIO: topProcesses(procs, top, func(ps *ProcessSummary) float64 {
+			return float64(ps.ReadBytesPerSecond + ps.WriteBytesPerSecond)
+		}),
+	}
+
+}
+
+// topProcesses returns the n processes with the highest non zero value
+func topProcesses(procs []ProcessSummary, n int, value func(*ProcessSummary) float64) []ProcessSummary {
+
+	var set []ProcessSummary
+	for i := range procs {
+		if value(&procs[i]) > 0 {
+			set = append(set, procs[i])
+		}
+	}
+
+	sort.SliceStable(set, func(i, j int) bool {
+		return value(&set[i]) > value(&set[j])
+	})
+
+	if len(set) > n {
+		set = set[:n]
+	}
+
+	return set
+
+}
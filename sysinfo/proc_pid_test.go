@@ -0,0 +1,81 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseProcInfo(t *testing.T) {
+
+	const output = `100
+pid 1
+1 (systemd) S 0 1 1 0 -1 4194560 48731 2218339 96 1022 312 418 5765 1882 20 0 1 0 4 172531712 2880 18446744073709551615 1 1 0 0 0 0 671173123 4096 1260 0 0 0 17 0 0 0 6 0 0 0 0 0 0 0 0 0 0
+Uid:	0	0	0	0
+VmRSS:	   11520 kB
+read_bytes: 251392000
+write_bytes: 48685056
+cmdline /sbin/init splash
+pid 2
+2 (kthreadd) S 0 0 0 0 -1 2129984 0 0 0 0 0 3 0 0 20 0 1 0 4 0 0 18446744073709551615 0 0 0 0 0 0 0 2147483647 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+Uid:	0	0	0	0
+cmdline 
+pid 812
+812 (my (odd) app) R 1 812 812 0 -1 4194560 1 0 0 0 1500 250 0 0 20 0 4 0 1800 0 9000 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 1 0 0 0 0 0 0 0 0 0 0 0 0 0
+Uid:	1000	1000	1000	1000
+VmRSS:	  204800 kB
+cmdline ./app --serve
+pid 913
+`
+
+	expected := ProcInfo{
+		ClockTicks: 100,
+		Processes: []Process{
+			{PID: 1, PPID: 0, Name: "systemd", Cmdline: "/sbin/init splash", State: "S", UID: 0, Threads: 1, StartTime: 4, UTime: 312, STime: 418, RSS: 11520 * 1024, ReadBytes: 251392000, WriteBytes: 48685056},
+			{PID: 2, PPID: 0, Name: "kthreadd", State: "S", Threads: 1, StartTime: 4, STime: 3},
+			{PID: 812, PPID: 1, Name: "my (odd) app", Cmdline: "./app --serve", State: "R", UID: 1000, Threads: 4, StartTime: 1800, UTime: 1500, STime: 250, RSS: 204800 * 1024},
+		},
+	}
+
+	var pi ProcInfo
+	if err := pi.Parse([]byte(output)); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(pi, expected) {
+		t.Error("parse mismatch")
+		dumpDiff(expected, pi)
+	}
+
+	// a second sample 2s later, where pid 812 has been reused
+	next := &ProcInfo{
+		ClockTicks: 100,
+		top:        2,
+		Processes: []Process{
+			{PID: 1, Name: "systemd", StartTime: 4, UTime: 322, STime: 428, RSS: 11520 * 1024, ReadBytes: 251392000 + 4096, WriteBytes: 48685056 + 8192},
+			{PID: 2, Name: "kthreadd", StartTime: 4, STime: 4},
+			{PID: 812, Name: "other", StartTime: 9000, UTime: 5000, RSS: 4096},
+		},
+	}
+
+	summary := next.Summarize(&pi, 2*time.Second).(*ProcInfoSummary)
+
+	if summary.Total != 3 {
+		t.Errorf("Expected 3 processes, got %d", summary.Total)
+	}
+	if len(summary.ByCPU) != 2 || summary.ByCPU[0].PID != 1 || summary.ByCPU[0].PercentCPU != 0.1 || summary.ByCPU[1].PID != 2 || summary.ByCPU[1].PercentCPU != 0.005 {
+		t.Errorf("Expected pids 1 and 2 by cpu, got %+v", summary.ByCPU)
+	}
+	if len(summary.ByRSS) != 2 || summary.ByRSS[0].PID != 1 || summary.ByRSS[1].PID != 812 {
+		t.Errorf("Expected pids 1 and 812 by rss, got %+v", summary.ByRSS)
+	}
+	if len(summary.ByIO) != 1 || summary.ByIO[0].ReadBytesPerSecond != 2048 || summary.ByIO[0].WriteBytesPerSecond != 4096 {
+		t.Errorf("Expected pid 1 by io, got %+v", summary.ByIO)
+	}
+
+	// the first sample has nothing to compare against
+	if summary := pi.Summarize(nil, 0).(*ProcInfoSummary); len(summary.ByCPU) != 0 || len(summary.ByRSS) != 2 {
+		t.Errorf("Expected only rss ranked, got %+v", summary)
+	}
+
+}
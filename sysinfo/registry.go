@@ -0,0 +1,72 @@
+package sysinfo
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// MetricFactory returns a new Metric to parse one sample into,
+	// configured by options, which may be nil
+	MetricFactory func(options map[string]string) (Metric, error)
+
+	// Summarizer is implemented by metrics that summarize themselves
+	// against the previous sample, nil for the first, duration apart.
+	// Other metrics are summarized as they are.
+	Summarizer interface {
+		Summarize(prev Metric, duration time.Duration) interface{}
+	}
+
+	// MetricConfig selects a registered metric for a collector
+	MetricConfig struct {
+		Name    string            `json:"name"`
+		Options map[string]string `json:"options,omitempty"`
+	}
+)
+
+var (
+	registry    = map[string]MetricFactory{}
+	registry_me sync.RWMutex
+)
+
+// Register makes a metric available to collectors by name, in addition
+// to the metrics of Stat.  The metric's command must exit 0 even if what
+// it reads is missing, as a failed command fails the whole sample.
+// Register panics if the name is already registered.
+func Register(name string, factory MetricFactory) {
+	registry_me.Lock()
+	defer registry_me.Unlock()
+	if _, exists := registry[name]; exists {
+		panic("sysinfo: Register called twice for metric " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of the registered metrics, sorted
+func Registered() []string {
+	registry_me.RLock()
+	defer registry_me.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a new instance of the configured metric
+func (mc MetricConfig) New() (Metric, error) {
+	registry_me.RLock()
+	factory, exists := registry[mc.Name]
+	registry_me.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown metric %q", mc.Name)
+	}
+	m, err := factory(mc.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", mc.Name, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,89 @@
+package sysinfo
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emptyinterface/window/sysinfo/test"
+	"golang.org/x/crypto/ssh"
+)
+
+type testCounter struct {
+	file  string
+	Count int
+}
+
+func (c *testCounter) Command() string { return "cat " + c.file }
+
+func (c *testCounter) Parse(b []byte) (err error) {
+	c.Count, err = strconv.Atoi(strings.TrimSpace(string(b)))
+	return
+}
+
+func (c *testCounter) Summarize(prev Metric, duration time.Duration) interface{} {
+	if prev == nil {
+		return 0
+	}
+	return c.Count - prev.(*testCounter).Count
+}
+
+func init() {
+	Register("test_counter", func(options map[string]string) (Metric, error) {
+		if len(options["file"]) == 0 {
+			return nil, errors.New("file is required")
+		}
+		return &testCounter{file: options["file"]}, nil
+	})
+}
+
+func TestRegistry(t *testing.T) {
+
+	if _, err := (MetricConfig{Name: "missing"}).New(); err == nil {
+		t.Error("Expected error for unregistered metric")
+	}
+	if _, err := (MetricConfig{Name: "test_counter"}).New(); err == nil || err.Error() != "test_counter: file is required" {
+		t.Errorf("Expected options error, got %v", err)
+	}
+
+	var req_num int
+	s := test.NewTestSSHExecServer(t, NewSSHGzipHandler(t, func(req *ssh.Request, input []byte) []byte {
+		if expected := TestCommand + " && echo -n " + commandDelimiter + " && cat /tmp/count"; string(input) != expected {
+			t.Errorf("Expected %q, got %q", expected, input)
+		}
+		req_num++
+		if req_num == 1 {
+			return []byte(TestOutput1 + commandDelimiter + "10\n")
+		}
+		return []byte(TestOutput2 + commandDelimiter + "13\n")
+	}))
+	defer s.Close()
+
+	sc := NewSystemInfoCollector(s.Host, &ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, 2)
+	defer sc.Close()
+	sc.Extra = []MetricConfig{{Name: "test_counter", Options: map[string]string{"file": "/tmp/count"}}}
+
+	if err := sc.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if summary := sc.GetSummary(); !reflect.DeepEqual(summary.Extra, map[string]interface{}{"test_counter": 0}) {
+		t.Errorf("Expected the first sample summarized alone, got %v", summary.Extra)
+	}
+
+	if err := sc.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	stats := sc.Stats.Last(1)
+	if c := stats[0].Extra["test_counter"].(*testCounter); c.Count != 13 {
+		t.Errorf("Expected 13, got %d", c.Count)
+	}
+	if summary := sc.GetSummary(); !reflect.DeepEqual(summary.Extra, map[string]interface{}{"test_counter": 3}) {
+		t.Errorf("Expected the difference, got %v", summary.Extra)
+	}
+
+}
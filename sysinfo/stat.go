@@ -12,6 +12,9 @@ type (
 		NetStat   NetStat
 		LoadAvg   LoadAvg
 		DiskInfo  DiskInfo
+
+		// registered metrics by name
+		Extra map[string]Metric
 	}
 )
 
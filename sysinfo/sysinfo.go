@@ -1,10 +1,14 @@
 package sysinfo
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -16,13 +20,31 @@ type (
 		Parse([]byte) error
 	}
 
+	// SystemInfoCollector keeps an ssh connection open to Host, opening a
+	// session on it for each Poll and reconnecting if it fails
 	SystemInfoCollector struct {
 		Host   string
 		Config *ssh.ClientConfig
 		Stats  *StatSeries
+
+		// how often the connection is checked, 0 disables keepalives
+		Keepalive time.Duration
+
+		// dials Host, e.g. through a jump host.  Defaults to a direct
+		// connection within DialTimeout.
+		Dial func(network, addr string) (net.Conn, error)
+
+		// registered metrics collected along with Stat's
+		Extra []MetricConfig
+
+		client *ssh.Client
+		stop   chan struct{}
+		me     sync.Mutex
 	}
 
 	SystemInfoSummary struct {
+		// SourceSSH or SourceCloudWatch
+		Source    string
 		Timestamp time.Time
 		Duration  time.Duration
 		Memory    struct {
@@ -52,7 +74,24 @@ type (
 			BytesOut          uint64
 			BytesPerSecondIn  uint64
 			BytesPerSecondOut uint64
+
+			// tcp segments retransmitted, and connections overflowing
+			// or dropped from listen queues
+			RetransmitsPerSecond     float64
+			ListenOverflowsPerSecond float64
+			ListenDropsPerSecond     float64
+		}
+		DiskIO struct {
+			ReadsPerSecond  float64
+			WritesPerSecond float64
 		}
+
+		// ec2 status, only available from cloudwatch
+		StatusCheckFailed bool
+		CPUCreditBalance  float64
+
+		// registered metrics by name, see Summarizer
+		Extra map[string]interface{} `json:",omitempty"`
 	}
 
 	CPUSummary struct {
@@ -73,8 +112,14 @@ type (
 
 const (
 	RemoteBashCommand = `/bin/gzip -d | /bin/bash | /bin/gzip`
+	// the script itself gzips each sample, see Stream
+	StreamBashCommand = `/bin/gzip -d | /bin/bash`
 	commandDelimiter  = `===Jj52dgpmaF===`
 	DialTimeout       = 2 * time.Second
+	KeepaliveInterval = 30 * time.Second
+
+	SourceSSH        = "ssh"
+	SourceCloudWatch = "cloudwatch"
 )
 
 var (
@@ -84,24 +129,51 @@ var (
 
 func NewSystemInfoCollector(host string, config *ssh.ClientConfig, entries int) *SystemInfoCollector {
 	return &SystemInfoCollector{
-		Host:   host,
-		Config: config,
-		Stats:  NewStatSeries(entries),
+		Host:      host,
+		Config:    config,
+		Stats:     NewStatSeries(entries),
+		Keepalive: KeepaliveInterval,
 	}
 }
 
-func (si *SystemInfoCollector) Poll() error {
+// newStat returns a new Stat with the collector's Extra metrics,
+// and all the metrics to collect into it in command order
+func (si *SystemInfoCollector) newStat() (*Stat, []Metric, error) {
 
 	stat := NewStat()
-
-	if err := si.Execute(
+	metrics := []Metric{
 		&stat.UpTime,
 		&stat.CPUInfo,
 		&stat.MemInfo,
 		&stat.NetStat,
 		&stat.LoadAvg,
 		&stat.DiskInfo,
-	); err != nil {
+	}
+
+	if len(si.Extra) > 0 {
+		stat.Extra = make(map[string]Metric, len(si.Extra))
+		for _, mc := range si.Extra {
+			m, err := mc.New()
+			if err != nil {
+				return nil, nil, err
+			}
+			stat.Extra[mc.Name] = m
+			metrics = append(metrics, m)
+		}
+	}
+
+	return stat, metrics, nil
+
+}
+
+func (si *SystemInfoCollector) Poll() error {
+
+	stat, metrics, err := si.newStat()
+	if err != nil {
+		return err
+	}
+
+	if err := si.Execute(metrics...); err != nil {
 		return err
 	}
 
@@ -113,22 +185,115 @@ func (si *SystemInfoCollector) Poll() error {
 
 }
 
-func (si *SystemInfoCollector) Execute(metrics ...Metric) error {
+// session opens a session on the collector's connection, dialing a new
+// connection if there is none or the existing one has failed
+func (si *SystemInfoCollector) session() (*ssh.Client, *ssh.Session, error) {
 
-	conn, err := net.DialTimeout("tcp", si.Host, DialTimeout)
+	si.me.Lock()
+	defer si.me.Unlock()
+
+	if si.client != nil {
+		if sess, err := si.client.NewSession(); err == nil {
+			return si.client, sess, nil
+		}
+		si.disconnect()
+	}
+
+	dial := si.Dial
+	if dial == nil {
+		dial = func(network, addr string) (net.Conn, error) {
+			return net.DialTimeout(network, addr, DialTimeout)
+		}
+	}
+
+	conn, err := dial("tcp", si.Host)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer conn.Close()
 
 	c, chans, reqs, err := ssh.NewClientConn(conn, si.Host, si.Config)
 	if err != nil {
-		return err
+		conn.Close()
+		return nil, nil, err
+	}
+	si.client = ssh.NewClient(c, chans, reqs)
+	si.stop = make(chan struct{})
+	if si.Keepalive > 0 {
+		go si.keepalive(si.client, si.stop)
+	}
+
+	sess, err := si.client.NewSession()
+	if err != nil {
+		si.disconnect()
+		return nil, nil, err
+	}
+
+	return si.client, sess, nil
+
+}
+
+// keepalive closes client if it stops answering keepalive requests
+func (si *SystemInfoCollector) keepalive(client *ssh.Client, stop chan struct{}) {
+
+	ticker := time.NewTicker(si.Keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		errc := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			errc <- err
+		}()
+		select {
+		case <-stop:
+			return
+		case err := <-errc:
+			if err == nil {
+				continue
+			}
+		case <-time.After(si.Keepalive):
+		}
+		si.drop(client)
+		return
+	}
+
+}
+
+// drop closes client if it is still the collector's connection
+func (si *SystemInfoCollector) drop(client *ssh.Client) {
+	si.me.Lock()
+	defer si.me.Unlock()
+	if si.client == client {
+		si.disconnect()
 	}
-	client := ssh.NewClient(c, chans, reqs)
-	defer client.Close()
+}
+
+// disconnect must be called with the lock held
+func (si *SystemInfoCollector) disconnect() {
+	close(si.stop)
+	si.client.Close()
+	si.client = nil
+}
+
+// Close closes the collector's connection, ending any Stream.
+// The next Poll reconnects.
+func (si *SystemInfoCollector) Close() error {
+	si.me.Lock()
+	defer si.me.Unlock()
+	if si.client != nil {
+		si.disconnect()
+	}
+	return nil
+}
+
+func (si *SystemInfoCollector) Execute(metrics ...Metric) error {
 
-	sess, err := client.NewSession()
+	client, sess, err := si.session()
 	if err != nil {
 		return err
 	}
@@ -146,6 +311,10 @@ func (si *SystemInfoCollector) Execute(metrics ...Metric) error {
 	sess.Stdout = &stdout
 
 	if err := sess.Run(RemoteBashCommand); err != nil {
+		if _, ok := err.(*ssh.ExitError); !ok {
+			// the connection broke, reconnect next time
+			si.drop(client)
+		}
 		return err
 	}
 
@@ -167,9 +336,112 @@ func (si *SystemInfoCollector) Execute(metrics ...Metric) error {
 		return err
 	}
 
+	return parse_output(data, metrics)
+
+}
+
+// Stream collects a sample every interval over a single session until
+// it ends or the collector is closed.  The remote end runs the commands
+// in a loop, gzipping each sample's output separately, and each sample
+// is added to Stats as it arrives.
+func (si *SystemInfoCollector) Stream(interval time.Duration) error {
+
+	seconds := int(interval.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	_, metrics, err := si.newStat()
+	if err != nil {
+		return err
+	}
+
+	client, sess, err := si.session()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	var stdin bytes.Buffer
+	gzw := gzip.NewWriter(&stdin)
+	fmt.Fprintf(gzw, "while :; do ( %s ) | /bin/gzip || exit; sleep %d; done", build_cmd(metrics), seconds)
+	gzw.Close()
+
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	sess.Stdin = &stdin
+
+	if err := sess.Start(StreamBashCommand); err != nil {
+		si.drop(client)
+		return err
+	}
+
+	br := bufio.NewReader(stdout)
+	var gzr *gzip.Reader
+
+	for {
+
+		// skip any shell noise before each sample
+		if err := skip_to_gzip_header(br); err != nil {
+			if err == io.EOF {
+				return sess.Wait()
+			}
+			return err
+		}
+
+		if gzr == nil {
+			gzr, err = gzip.NewReader(br)
+		} else {
+			err = gzr.Reset(br)
+		}
+		if err != nil {
+			return err
+		}
+		// one sample per gzip member
+		gzr.Multistream(false)
+
+		data, err := ioutil.ReadAll(gzr)
+		if err != nil {
+			return err
+		}
+
+		stat, metrics, err := si.newStat()
+		if err != nil {
+			return err
+		}
+		if err := parse_output(data, metrics); err != nil {
+			return err
+		}
+		stat.Timestamp = time.Now()
+		si.Stats.Add(stat)
+
+	}
+
+}
+
+func skip_to_gzip_header(br *bufio.Reader) error {
+	for {
+		b, err := br.Peek(len(gzipHeaderByteSequence))
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(b, gzipHeaderByteSequence) {
+			return nil
+		}
+		br.Discard(1)
+	}
+}
+
+func parse_output(data []byte, metrics []Metric) error {
+
 	stdouts := bytes.Split(data, []byte(commandDelimiter))
 
 	for i, stdout := range stdouts {
+		if i >= len(metrics) {
+			break
+		}
 		if err := metrics[i].Parse(stdout); err != nil {
 			return err
 		}
@@ -209,6 +481,7 @@ func (si *SystemInfoCollector) GetSummary() *SystemInfoSummary {
 	}
 
 	s := &SystemInfoSummary{
+		Source:    SourceSSH,
 		Timestamp: current.Timestamp,
 	}
 
@@ -257,12 +530,36 @@ func (si *SystemInfoCollector) GetSummary() *SystemInfoSummary {
 		s.Network.BytesOut = current.NetStat.OutOctets - prev.NetStat.OutOctets
 		s.Network.BytesPerSecondIn = uint64(float64(s.Network.BytesIn) / seconds)
 		s.Network.BytesPerSecondOut = uint64(float64(s.Network.BytesOut) / seconds)
+		s.Network.RetransmitsPerSecond = float64(retransmits(&current.NetStat)-retransmits(&prev.NetStat)) / seconds
+		s.Network.ListenOverflowsPerSecond = float64(current.NetStat.ListenOverflows-prev.NetStat.ListenOverflows) / seconds
+		s.Network.ListenDropsPerSecond = float64(current.NetStat.ListenDrops-prev.NetStat.ListenDrops) / seconds
+	}
+
+	if len(current.Extra) > 0 {
+		s.Extra = make(map[string]interface{}, len(current.Extra))
+		for name, m := range current.Extra {
+			summarizer, ok := m.(Summarizer)
+			if !ok {
+				s.Extra[name] = m
+				continue
+			}
+			var last Metric
+			if prev != nil {
+				last = prev.Extra[name]
+			}
+			s.Extra[name] = summarizer.Summarize(last, s.Duration)
+		}
 	}
 
 	return s
 
 }
 
+// retransmits totals the TcpExt retransmission counters
+func retransmits(ns *NetStat) uint64 {
+	return ns.TCPFastRetrans + ns.TCPForwardRetrans + ns.TCPSlowStartRetrans + ns.TCPSynRetrans
+}
+
 func calculate_cpu_stats(summary *CPUSummary, prev, current *CPU) {
 
 	var (
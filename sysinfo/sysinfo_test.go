@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -53,11 +54,9 @@ func NewSSHGzipHandler(t *testing.T, h func(req *ssh.Request, input []byte) (out
 	}
 }
 
-func TestSystemInformationCollector(t *testing.T) {
-
-	const (
-		TestCommand = `cat /proc/uptime && echo -n ===Jj52dgpmaF=== && cat /proc/stat && echo -n ===Jj52dgpmaF=== && cat /proc/meminfo && echo -n ===Jj52dgpmaF=== && cat /proc/net/netstat && echo -n ===Jj52dgpmaF=== && cat /proc/loadavg && echo -n ===Jj52dgpmaF=== && df -B1 && echo -n ===aRVZeDergP=== && df -i`
-		TestOutput1 = `634791.08 5077082.86
+const (
+	TestCommand = `cat /proc/uptime && echo -n ===Jj52dgpmaF=== && cat /proc/stat && echo -n ===Jj52dgpmaF=== && cat /proc/meminfo && echo -n ===Jj52dgpmaF=== && cat /proc/net/netstat && echo -n ===Jj52dgpmaF=== && cat /proc/loadavg && echo -n ===Jj52dgpmaF=== && df -B1 && echo -n ===aRVZeDergP=== && df -i`
+	TestOutput1 = `634791.08 5077082.86
 ===Jj52dgpmaF===cpu  42642 5461 18868 507226813 12965 3 452 22329 0 0
 cpu0 2572 1692 1703 63401671 7941 0 1 2594 0 0
 cpu1 24496 90 7268 63405853 617 2 450 1961 0 0
@@ -136,7 +135,7 @@ none            127868      1  127867    1% /run/shm
 cgroup          127868     12  127856    1% /sys/fs/cgroup
 /dev/xvdc      2424832  51487 2373345    3% /storage
 `
-		TestOutput2 = `634793.22 5077099.55
+	TestOutput2 = `634793.22 5077099.55
 ===Jj52dgpmaF===cpu  42665 5461 18907 507228463 12965 3 452 22329 0 0
 cpu0 2572 1692 1704 63401884 7941 0 1 2594 0 0
 cpu1 24497 90 7269 63406062 617 2 450 1961 0 0
@@ -215,7 +214,9 @@ none            127868      1  127867    1% /run/shm
 cgroup          127868     12  127856    1% /sys/fs/cgroup
 /dev/xvdc      2424832  51487 2373345    3% /storage
 `
-	)
+)
+
+func TestSystemInformationCollector(t *testing.T) {
 
 	var req_num int
 	s := test.NewTestSSHExecServer(t, NewSSHGzipHandler(t, func(req *ssh.Request, input []byte) []byte {
@@ -253,6 +254,12 @@ cgroup          127868     12  127856    1% /sys/fs/cgroup
 		t.Error(err)
 	}
 
+	// both polls share a connection
+	if n := s.Connections(); n != 1 {
+		t.Errorf("Expected 1 connection, got %d", n)
+	}
+	sc.Close()
+
 	summary := sc.GetSummary()
 
 	// pretty.Println(summary)
@@ -261,6 +268,7 @@ cgroup          127868     12  127856    1% /sys/fs/cgroup
 	summary.Timestamp = time.Time{}
 
 	expected := &SystemInfoSummary{
+		Source:    SourceSSH,
 		Timestamp: time.Time{},
 		Duration:  2140000000,
 		Memory: struct {
@@ -305,6 +313,10 @@ cgroup          127868     12  127856    1% /sys/fs/cgroup
 			BytesOut          uint64
 			BytesPerSecondIn  uint64
 			BytesPerSecondOut uint64
+
+			RetransmitsPerSecond     float64
+			ListenOverflowsPerSecond float64
+			ListenDropsPerSecond     float64
 		}{BytesIn: 0x16da, BytesOut: 0x207d, BytesPerSecondIn: 0xaad, BytesPerSecondOut: 0xf2e},
 	}
 
@@ -315,6 +327,114 @@ cgroup          127868     12  127856    1% /sys/fs/cgroup
 
 }
 
+func TestSystemInformationCollectorTCPRates(t *testing.T) {
+
+	sc := NewSystemInfoCollector("", nil, 2)
+
+	prev, current := NewStat(), NewStat()
+	prev.UpTime.Total = 100 * time.Second
+	prev.NetStat.TCPFastRetrans, prev.NetStat.TCPSynRetrans = 10, 404
+	prev.NetStat.ListenOverflows, prev.NetStat.ListenDrops = 1, 1
+	current.UpTime.Total = 104 * time.Second
+	current.NetStat.TCPFastRetrans, current.NetStat.TCPForwardRetrans, current.NetStat.TCPSlowStartRetrans, current.NetStat.TCPSynRetrans = 14, 2, 2, 408
+	current.NetStat.ListenOverflows, current.NetStat.ListenDrops = 3, 5
+	sc.Stats.Add(prev)
+	sc.Stats.Add(current)
+
+	summary := sc.GetSummary()
+	if summary.Network.RetransmitsPerSecond != 3 {
+		t.Errorf("Expected 3 retransmits/s, got %v", summary.Network.RetransmitsPerSecond)
+	}
+	if summary.Network.ListenOverflowsPerSecond != 0.5 {
+		t.Errorf("Expected 0.5 listen overflows/s, got %v", summary.Network.ListenOverflowsPerSecond)
+	}
+	if summary.Network.ListenDropsPerSecond != 1 {
+		t.Errorf("Expected 1 listen drop/s, got %v", summary.Network.ListenDropsPerSecond)
+	}
+
+}
+
+func TestSystemInformationCollectorDial(t *testing.T) {
+
+	s := test.NewTestSSHExecServer(t, NewSSHGzipHandler(t, func(req *ssh.Request, input []byte) []byte {
+		return []byte(TestOutput1)
+	}))
+	defer s.Close()
+
+	sc := NewSystemInfoCollector("instance:22", &ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, 2)
+	defer sc.Close()
+
+	var dialed []string
+	sc.Dial = func(network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return net.Dial(network, s.Host)
+	}
+
+	if err := sc.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(dialed) != 1 || dialed[0] != "instance:22" {
+		t.Errorf("Expected instance:22 dialed, got %v", dialed)
+	}
+
+}
+
+func TestSystemInformationCollectorStream(t *testing.T) {
+
+	s := test.NewTestSSHExecServer(t, func(req *ssh.Request, channel ssh.Channel) {
+
+		if reqBashCommand := string(req.Payload); reqBashCommand != StreamBashCommand {
+			t.Errorf("Expected %q, got %q", StreamBashCommand, reqBashCommand)
+		}
+
+		gzr, err := gzip.NewReader(channel)
+		if err != nil {
+			t.Error(err)
+		}
+		input, err := ioutil.ReadAll(gzr)
+		if err != nil {
+			t.Error(err)
+		}
+		if expected := "while :; do ( " + TestCommand + " ) | /bin/gzip || exit; sleep 1; done"; string(input) != expected {
+			t.Errorf("Expected %q, got %q", expected, input)
+		}
+
+		// a sample per gzip member, with some shell noise
+		channel.Write([]byte("Welcome!\n"))
+		for _, output := range []string{TestOutput1, TestOutput2} {
+			gzw := gzip.NewWriter(channel)
+			gzw.Write([]byte(output))
+			if err := gzw.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+
+		channel.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
+		channel.Close()
+
+	})
+	defer s.Close()
+
+	sc := NewSystemInfoCollector(s.Host, &ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, 2)
+	defer sc.Close()
+
+	if err := sc.Stream(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := sc.Stats.Len(); n != 2 {
+		t.Fatalf("Expected 2 samples, got %d", n)
+	}
+	if summary := sc.GetSummary(); summary.Duration != 2140000000 {
+		t.Errorf("Expected a summary of both samples, got %v", summary.Duration)
+	}
+
+}
+
 func dumpDiff(expected, actual interface{}) {
 
 	expjson, err := json.Marshal(expected)
@@ -0,0 +1,164 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// SystemdUnits reports the state of failed units and of any watched
+	// units, with the last journal lines of those that failed
+	SystemdUnits struct {
+		Units []Unit
+
+		watched      []string
+		journalLines int
+	}
+
+	Unit struct {
+		Name        string
+		LoadState   string
+		ActiveState string
+		SubState    string
+		// times systemd restarted the service, 0 for other unit types
+		Restarts int
+		Watched  bool
+		// the last lines logged by a failed unit, only readable by root
+		// or members of the systemd-journal and adm groups
+		Journal []string
+	}
+)
+
+const DefaultJournalLines = 5
+
+var unitNameRegexp = regexp.MustCompile(`^[A-Za-z0-9:_.@-]+$`)
+
+func init() {
+	Register("systemd", func(options map[string]string) (Metric, error) {
+		su := &SystemdUnits{journalLines: DefaultJournalLines}
+		if units, exists := options["units"]; exists {
+			for _, name := range strings.Split(units, ",") {
+				name = strings.TrimSpace(name)
+				if !unitNameRegexp.MatchString(name) {
+					return nil, fmt.Errorf("invalid unit %q", name)
+				}
+				su.watched = append(su.watched, name)
+			}
+		}
+		if lines, exists := options["journal_lines"]; exists {
+			n, err := strconv.Atoi(lines)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid journal_lines %q", lines)
+			}
+			su.journalLines = n
+		}
+		return su, nil
+	})
+}
+
+func (su *SystemdUnits) Command() string {
+	return `( for u in $(systemctl list-units --failed --plain --no-legend --no-pager | awk '{print $1}') ` + strings.Join(su.watched, " ") + `; do ` +
+		`echo "unit $u"; systemctl show "$u" --no-pager --property=LoadState,ActiveState,SubState,NRestarts; ` +
+		`if [ "$(systemctl is-failed "$u")" = failed ]; then journalctl -u "$u" -n ` + strconv.Itoa(su.journalLines) + ` --no-pager -o cat | sed 's/^/journal /'; fi; ` +
+		`done; true ) 2>/dev/null`
+}
+
+func (su *SystemdUnits) Parse(b []byte) error {
+
+	// output:
+	// unit nginx.service
+	// LoadState=loaded
+	// ActiveState=failed
+	// SubState=failed
+	// NRestarts=5
+	// journal nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)
+
+	var unit *Unit
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1<<20)
+
+	for s.Scan() {
+
+		line := s.Text()
+
+		if strings.HasPrefix(line, "unit ") {
+			name := strings.TrimSpace(line[len("unit "):])
+			unit = nil
+			// a failed unit that is also watched is listed twice
+			for i := range su.Units {
+				if su.Units[i].Name == name {
+					unit = &su.Units[i]
+					*unit = Unit{Name: name}
+					break
+				}
+			}
+			if unit == nil {
+				su.Units = append(su.Units, Unit{Name: name})
+				unit = &su.Units[len(su.Units)-1]
+			}
+			continue
+		}
+		if unit == nil {
+			continue
+		}
+
+		if strings.HasPrefix(line, "journal ") {
+			unit.Journal = append(unit.Journal, line[len("journal "):])
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "LoadState":
+			unit.LoadState = kv[1]
+		case "ActiveState":
+			unit.ActiveState = kv[1]
+		case "SubState":
+			unit.SubState = kv[1]
+		case "NRestarts":
+			unit.Restarts, _ = strconv.Atoi(kv[1])
+		}
+
+	}
+
+	for i := range su.Units {
+		for _, name := range su.watched {
+			if su.Units[i].Name == name {
+				su.Units[i].Watched = true
+				break
+			}
+		}
+	}
+
+	return s.Err()
+
+}
+
+// Failed returns the units that failed
+func (su *SystemdUnits) Failed() []Unit {
+	var failed []Unit
+	for _, unit := range su.Units {
+		if unit.Failed() {
+			failed = append(failed, unit)
+		}
+	}
+	return failed
+}
+
+// Failed reports whether the unit is in the failed state
+func (u Unit) Failed() bool {
+	return u.ActiveState == "failed"
+}
+
+// Down reports whether a watched unit isn't running, or is missing
+func (u Unit) Down() bool {
+	return u.Watched && (u.LoadState != "loaded" || (u.ActiveState != "active" && u.ActiveState != "reloading"))
+}
@@ -0,0 +1,89 @@
+package sysinfo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSystemdUnits(t *testing.T) {
+
+	const output = `unit nginx.service
+LoadState=loaded
+ActiveState=failed
+SubState=failed
+NRestarts=5
+journal nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)
+journal nginx.service: Failed with result 'exit-code'.
+unit logrotate.timer
+LoadState=loaded
+ActiveState=failed
+SubState=failed
+unit nginx.service
+LoadState=loaded
+ActiveState=failed
+SubState=failed
+NRestarts=5
+journal nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)
+journal nginx.service: Failed with result 'exit-code'.
+unit sshd.service
+LoadState=loaded
+ActiveState=active
+SubState=running
+NRestarts=0
+unit redis.service
+LoadState=not-found
+ActiveState=inactive
+SubState=dead
+NRestarts=0
+`
+
+	m, err := MetricConfig{Name: "systemd", Options: map[string]string{"units": "nginx.service, sshd.service,redis.service", "journal_lines": "2"}}.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	su := m.(*SystemdUnits)
+
+	if cmd := su.Command(); !strings.Contains(cmd, " nginx.service sshd.service redis.service; do") || !strings.Contains(cmd, "-n 2 ") {
+		t.Errorf("Expected watched units and journal lines in command, got %s", cmd)
+	}
+
+	if err := su.Parse([]byte(output)); err != nil {
+		t.Error(err)
+	}
+
+	expected := []Unit{
+		{Name: "nginx.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed", Restarts: 5, Watched: true, Journal: []string{
+			"nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)",
+			"nginx.service: Failed with result 'exit-code'.",
+		}},
+		{Name: "logrotate.timer", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+		{Name: "sshd.service", LoadState: "loaded", ActiveState: "active", SubState: "running", Watched: true},
+		{Name: "redis.service", LoadState: "not-found", ActiveState: "inactive", SubState: "dead", Watched: true},
+	}
+
+	if !reflect.DeepEqual(su.Units, expected) {
+		t.Error("parse mismatch")
+		dumpDiff(expected, su.Units)
+	}
+
+	if failed := su.Failed(); len(failed) != 2 || failed[0].Name != "nginx.service" || failed[1].Name != "logrotate.timer" {
+		t.Errorf("Expected nginx and logrotate failed, got %v", failed)
+	}
+	for _, unit := range su.Units {
+		if down := unit.Name == "nginx.service" || unit.Name == "redis.service"; unit.Down() != down {
+			t.Errorf("%s: Expected down %v", unit.Name, down)
+		}
+	}
+
+	for _, options := range []map[string]string{
+		{"units": "nginx.service; reboot"},
+		{"units": ""},
+		{"journal_lines": "-1"},
+	} {
+		if _, err := (MetricConfig{Name: "systemd", Options: options}).New(); err == nil {
+			t.Errorf("Expected error for options %v", options)
+		}
+	}
+
+}
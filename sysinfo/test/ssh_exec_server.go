@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"golang.org/x/crypto/ssh"
@@ -13,8 +14,9 @@ import (
 
 type (
 	SSHExecServer struct {
-		Host string
-		l    net.Listener
+		Host  string
+		l     net.Listener
+		conns int32
 	}
 )
 
@@ -73,6 +75,11 @@ func NewTestSSHExecServer(t *testing.T, f func(req *ssh.Request, channel ssh.Cha
 		t.Fatal("failed to acquire tcp listener")
 	}
 
+	s := &SSHExecServer{
+		Host: l.Addr().String(),
+		l:    l,
+	}
+
 	go func() {
 
 		for {
@@ -85,6 +92,7 @@ func NewTestSSHExecServer(t *testing.T, f func(req *ssh.Request, channel ssh.Cha
 				t.Error(err)
 				t.Fatal("failed to accept incoming connection")
 			}
+			atomic.AddInt32(&s.conns, 1)
 			go func(conn net.Conn) {
 				defer conn.Close()
 
@@ -134,11 +142,13 @@ func NewTestSSHExecServer(t *testing.T, f func(req *ssh.Request, channel ssh.Cha
 
 	}()
 
-	return &SSHExecServer{
-		Host: l.Addr().String(),
-		l:    l,
-	}
+	return s
+
+}
 
+// Connections returns the number of connections accepted
+func (s *SSHExecServer) Connections() int {
+	return int(atomic.LoadInt32(&s.conns))
 }
 
 func (s *SSHExecServer) Close() error {
@@ -0,0 +1,104 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+type (
+	// SysInfoConfig selects the registered sysinfo metrics collected from
+	// each instance, on top of the standard ones, e.g.
+	//
+	//	{
+	//		"default": ["procinfo"],
+	//		"tags": {"role=db": ["diskstats"], "docker": ["containers"]},
+	//		"instances": {"i-0123456789abcdef0": ["systemd"]},
+	//		"options": {"systemd": {"units": "nginx.service"}}
+	//	}
+	//
+	// Tags match as key=value, or by key alone.  An instance gets the
+	// metrics of the default, every matching tag and its id.
+	SysInfoConfig struct {
+		Default   []string                     `json:"default"`
+		Tags      map[string][]string          `json:"tags"`
+		Instances map[string][]string          `json:"instances"`
+		Options   map[string]map[string]string `json:"options"`
+	}
+)
+
+// LoadSysInfoConfig reads a json sysinfo config, checking that
+// each metric it names is registered and accepts its options
+func LoadSysInfoConfig(path string) (*SysInfoConfig, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c SysInfoConfig
+	if err := json.NewDecoder(f).Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	names := append([]string{}, c.Default...)
+	for _, set := range c.Tags {
+		names = append(names, set...)
+	}
+	for _, set := range c.Instances {
+		names = append(names, set...)
+	}
+	for _, name := range names {
+		if _, err := c.metric(name).New(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	return &c, nil
+
+}
+
+func (c *SysInfoConfig) metric(name string) sysinfo.MetricConfig {
+	return sysinfo.MetricConfig{Name: name, Options: c.Options[name]}
+}
+
+// For returns the metrics to collect from inst, the default first,
+// then those of its tags and its id
+func (c *SysInfoConfig) For(inst *Instance) []sysinfo.MetricConfig {
+
+	if c == nil {
+		return nil
+	}
+
+	var (
+		metrics []sysinfo.MetricConfig
+		seen    = map[string]bool{}
+	)
+
+	add := func(names []string) {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				metrics = append(metrics, c.metric(name))
+			}
+		}
+	}
+
+	add(c.Default)
+	for _, tag := range inst.Tags {
+		if tag.Key == nil {
+			continue
+		}
+		add(c.Tags[*tag.Key])
+		if tag.Value != nil {
+			add(c.Tags[*tag.Key+"="+*tag.Value])
+		}
+	}
+	add(c.Instances[inst.InstanceId])
+
+	return metrics
+
+}
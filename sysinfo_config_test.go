@@ -0,0 +1,101 @@
+package window
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/emptyinterface/window/sysinfo"
+)
+
+func init() {
+	for _, name := range []string{"test_a", "test_b", "test_c"} {
+		sysinfo.Register(name, func(options map[string]string) (sysinfo.Metric, error) {
+			if options["bad"] == "true" {
+				return nil, errors.New("bad option")
+			}
+			return new(sysinfo.LoadAvg), nil
+		})
+	}
+}
+
+func TestLoadSysInfoConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "window-sysinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		json string
+		err  string
+	}{
+		{`{"default": ["test_a"], "tags": {"role=db": ["test_b"]}}`, ""},
+		{`{"instances": {"i-1": ["test_missing"]}}`, `unknown metric "test_missing"`},
+		{`{"default": ["test_a"], "options": {"test_a": {"bad": "true"}}}`, "test_a: bad option"},
+		// the SysInfoConfig example
+		{`{"default": ["procinfo"], "tags": {"role=db": ["diskstats"], "docker": ["containers"]}, "instances": {"i-0123456789abcdef0": ["systemd"]}, "options": {"systemd": {"units": "nginx.service"}}}`, ""},
+	} {
+		path := filepath.Join(dir, "sysinfo.json")
+		if err := ioutil.WriteFile(path, []byte(test.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadSysInfoConfig(path)
+		switch {
+		case len(test.err) == 0 && err != nil:
+			t.Errorf("%s: %v", test.json, err)
+		case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: Expected error %q, got %v", test.json, test.err, err)
+		}
+	}
+
+}
+
+func TestSysInfoConfigFor(t *testing.T) {
+
+	c := &SysInfoConfig{
+		Default:   []string{"test_a"},
+		Tags:      map[string][]string{"role=db": {"test_b"}, "role=web": {"test_c"}, "docker": {"test_c", "test_a"}},
+		Instances: map[string][]string{"i-1": {"test_c"}},
+		Options:   map[string]map[string]string{"test_b": {"device": "xvdf"}},
+	}
+
+	names := func(metrics []sysinfo.MetricConfig) (s []string) {
+		for _, m := range metrics {
+			s = append(s, m.Name)
+		}
+		return
+	}
+
+	inst := &Instance{
+		InstanceId: "i-2",
+		Tags:       []*ec2.Tag{{Key: aws.String("role"), Value: aws.String("db")}},
+	}
+	metrics := c.For(inst)
+	if n := names(metrics); !reflect.DeepEqual(n, []string{"test_a", "test_b"}) {
+		t.Errorf("Expected default and tag metrics, got %v", n)
+	}
+	if metrics[1].Options["device"] != "xvdf" {
+		t.Errorf("Expected test_b's options, got %v", metrics[1].Options)
+	}
+
+	inst = &Instance{
+		InstanceId: "i-1",
+		Tags:       []*ec2.Tag{{Key: aws.String("docker"), Value: aws.String("")}},
+	}
+	if n := names(c.For(inst)); !reflect.DeepEqual(n, []string{"test_a", "test_c"}) {
+		t.Errorf("Expected each metric once, got %v", n)
+	}
+
+	if metrics := (*SysInfoConfig)(nil).For(inst); metrics != nil {
+		t.Errorf("Expected no metrics without a config, got %v", metrics)
+	}
+
+}
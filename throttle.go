@@ -2,6 +2,7 @@ package window
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,6 +11,9 @@ type (
 		concurrent chan struct{}
 		rate       chan struct{}
 		ticker     *time.Ticker
+
+		// calls waiting for a concurrency or rate slot
+		queued int64
 	}
 )
 
@@ -43,8 +47,10 @@ func (t *throttle) do(name string, f func() error) chan error {
 
 	go func() {
 		var err error
+		atomic.AddInt64(&t.queued, 1)
 		t.concurrent <- struct{}{}
 		t.rate <- struct{}{}
+		atomic.AddInt64(&t.queued, -1)
 		defer func() {
 			<-t.concurrent
 			// if e := recover(); e != nil {
@@ -65,6 +71,16 @@ func (t *throttle) do(name string, f func() error) chan error {
 
 }
 
+// Queued returns the number of calls waiting to run
+func (t *throttle) Queued() int {
+	return int(atomic.LoadInt64(&t.queued))
+}
+
+// Running returns the number of calls running
+func (t *throttle) Running() int {
+	return len(t.concurrent)
+}
+
 func (t *throttle) stop() {
 	t.ticker.Stop()
 }
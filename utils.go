@@ -224,6 +224,14 @@ func VPNConnectionInSlice(haystack []*VPNConnection, needle *VPNConnection) bool
 	}
 	return false
 }
+func StringInSlice(haystack []string, needle string) bool {
+	for _, item := range haystack {
+		if item == needle {
+			return true
+		}
+	}
+	return false
+}
 
 func string_less_than(a, b string) bool {
 
@@ -34,6 +34,7 @@ type (
 
 		Name                  string
 		Id                    string
+		AccountId             string
 		CIDR                  *net.IPNet
 		Region                *Region
 		ACLs                  []*ACL
@@ -76,9 +77,9 @@ func (vpc *VPC) String() string {
 	return ""
 }
 
-func LoadVPCs(input *ec2.DescribeVpcsInput) (map[string]*VPC, error) {
+func LoadVPCs(client EC2API, input *ec2.DescribeVpcsInput) (map[string]*VPC, error) {
 
-	resp, err := EC2Client.DescribeVpcs(input)
+	resp, err := client.DescribeVpcs(input)
 	if err != nil {
 		return nil, err
 	}
@@ -46,11 +46,12 @@ type (
 		// The ID of the VPC to which the endpoint is associated.
 		VpcId string
 
-		Name    string
-		Id      string
-		Policy  VPCEPolicy
-		VPC     *VPC
-		Subnets []*Subnet
+		Name      string
+		Id        string
+		AccountId string
+		Policy    VPCEPolicy
+		VPC       *VPC
+		Subnets   []*Subnet
 	}
 
 	VPCEndpointByNameAsc []*VPCEndpoint
@@ -62,9 +63,9 @@ func (a VPCEndpointByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadVPCEndpoints(input *ec2.DescribeVpcEndpointsInput) (map[string]*VPCEndpoint, error) {
+func LoadVPCEndpoints(client EC2API, input *ec2.DescribeVpcEndpointsInput) (map[string]*VPCEndpoint, error) {
 
-	resp, err := EC2Client.DescribeVpcEndpoints(input)
+	resp, err := client.DescribeVpcEndpoints(input)
 	if err != nil {
 		return nil, err
 	}
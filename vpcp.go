@@ -29,6 +29,7 @@ type (
 
 		Name         string
 		Id           string
+		AccountId    string
 		State        string
 		RequesterVPC *VPC
 		AccepterVPC  *VPC
@@ -44,9 +45,9 @@ func (a VPCPeeringConnectionByNameAsc) Less(i, j int) bool {
 	return string_less_than(a[i].Name, a[j].Name)
 }
 
-func LoadVPCPeeringConnections(input *ec2.DescribeVpcPeeringConnectionsInput) (map[string]*VPCPeeringConnection, error) {
+func LoadVPCPeeringConnections(client EC2API, input *ec2.DescribeVpcPeeringConnectionsInput) (map[string]*VPCPeeringConnection, error) {
 
-	resp, err := EC2Client.DescribeVpcPeeringConnections(input)
+	resp, err := client.DescribeVpcPeeringConnections(input)
 	if err != nil {
 		return nil, err
 	}
@@ -26,6 +26,7 @@ type (
 
 		Name             string
 		Id               string
+		AccountId        string
 		VPCs             []*VPC
 		VPNConnections   []*VPNConnection
 		Subnets          []*Subnet
@@ -48,9 +49,9 @@ func (vpg *VPGateway) String() string {
 	return ""
 }
 
-func LoadVPGateways(input *ec2.DescribeVpnGatewaysInput) (map[string]*VPGateway, error) {
+func LoadVPGateways(client EC2API, input *ec2.DescribeVpnGatewaysInput) (map[string]*VPGateway, error) {
 
-	resp, err := EC2Client.DescribeVpnGateways(input)
+	resp, err := client.DescribeVpnGateways(input)
 	if err != nil {
 		return nil, err
 	}
@@ -44,6 +44,7 @@ type (
 
 		Name                       string
 		Id                         string
+		AccountId                  string
 		VPNConnectionConfiguration *VPNConnectionConfiguration
 		VPGateway                  *VPGateway
 		CustomerGateway            *CustomerGateway
@@ -66,9 +67,9 @@ func (vpn *VPNConnection) String() string {
 	return ""
 }
 
-func LoadVPNConnections(input *ec2.DescribeVpnConnectionsInput) (map[string]*VPNConnection, error) {
+func LoadVPNConnections(client EC2API, input *ec2.DescribeVpnConnectionsInput) (map[string]*VPNConnection, error) {
 
-	resp, err := EC2Client.DescribeVpnConnections(input)
+	resp, err := client.DescribeVpnConnections(input)
 	if err != nil {
 		return nil, err
 	}
@@ -4,24 +4,13 @@ import (
 	"fmt"
 	"sort"
 	"sync"
-
-	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/cloudwatch"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/elasticache"
-	"github.com/aws/aws-sdk-go/service/elb"
-	"github.com/aws/aws-sdk-go/service/iam"
-	"github.com/aws/aws-sdk-go/service/lambda"
-	"github.com/aws/aws-sdk-go/service/rds"
-	"github.com/aws/aws-sdk-go/service/sns"
-	"github.com/aws/aws-sdk-go/service/sqs"
 )
 
 type (
 	Tracker struct {
 		counts map[string]int
+		// counts since startup, not reset by Report
+		totals map[string]int
 		me     sync.Mutex
 	}
 )
@@ -31,45 +20,34 @@ const (
 )
 
 var (
-	sess             *session.Session
-	ELBClient        *elb.ELB
-	EC2Client        *ec2.EC2
-	ASClient         *autoscaling.AutoScaling
-	ECClient         *elasticache.ElastiCache
-	RDSClient        *rds.RDS
-	CloudWatchClient *cloudwatch.CloudWatch
-	SQSClient        *sqs.SQS
-	SNSClient        *sns.SNS
-	LambdaClient     *lambda.Lambda
-	IAMClient        *iam.IAM
-
 	tracker = &Tracker{
 		counts: map[string]int{},
+		totals: map[string]int{},
 		me:     sync.Mutex{},
 	}
 )
 
-func init() {
-	sess = session.New(nil)
-	sess.Handlers.Send.PushBack(func(req *request.Request) {
-		tracker.Increment(req.ClientInfo.ServiceName)
-	})
-	ELBClient = elb.New(sess)
-	EC2Client = ec2.New(sess)
-	ASClient = autoscaling.New(sess)
-	ECClient = elasticache.New(sess)
-	RDSClient = rds.New(sess)
-	CloudWatchClient = cloudwatch.New(sess)
-	SQSClient = sqs.New(sess)
-	SNSClient = sns.New(sess)
-	LambdaClient = lambda.New(sess)
-	IAMClient = iam.New(sess)
-}
-
 func (t *Tracker) Increment(service string) {
 	t.me.Lock()
 	defer t.me.Unlock()
 	t.counts[service]++
+	t.totals[service]++
+}
+
+// Totals returns the number of api calls made to each service since startup
+func (t *Tracker) Totals() map[string]int {
+	t.me.Lock()
+	defer t.me.Unlock()
+	totals := make(map[string]int, len(t.totals))
+	for name, ct := range t.totals {
+		totals[name] = ct
+	}
+	return totals
+}
+
+// APICalls returns the number of api calls made to each service since startup
+func APICalls() map[string]int {
+	return tracker.Totals()
 }
 
 func (t *Tracker) Report() {